@@ -7,8 +7,8 @@
 // through additional arguments and caching mechanisms.
 //
 // Key Features:
-// - Support for multiple CPU architectures (x86_64, aarch64, armv7, ppc64le, s390x).
-// - Configuration management for APKO builds.
+// - Support for multiple CPU architectures (x86_64, aarch64, armv7, ppc64le, s390x), including multi-arch builds.
+// - Configuration management for APKO builds, including a typed model of the apko YAML configuration.
 // - Keyring handling for package verification.
 // - Caching mechanisms to optimize build processes.
 // - High-level interface for building and managing APKO images.
@@ -44,8 +44,13 @@ package apkox
 
 import (
 	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/Excoriate/daggerx/pkg/containerx"
 	"github.com/Excoriate/daggerx/pkg/fixtures"
 )
 
@@ -64,7 +69,7 @@ const (
 	// ArchS390x represents the s390x architecture
 	ArchS390x Architecture = "s390x"
 	// ApkoDefaultRepositoryURL is the default repository URL for APKO builds
-	ApkoDefaultRepositoryURL = "cgr.dev/chainguard/apko"
+	ApkoDefaultRepositoryURL = fixtures.ApkoImage
 	// ApkoWolfiSigninRsaKeyPath is the path to the Wolfi signing RSA public key used for package verification.
 	ApkoWolfiSigninRsaKeyPath = "/etc/apk/keys/wolfi-signing.rsa.pub"
 	// ApkoAlpineSigninRsaKeyPath is the path to the Alpine signing RSA public key used for package verification.
@@ -101,9 +106,21 @@ type ApkoBuilder struct {
 	// alpineKeyring indicates whether to use the Alpine keyring.
 	alpineKeyring bool
 
+	// keyringPresets is a slice of keyring preset names resolved when the command is generated.
+	keyringPresets []string
+
+	// keyringRegistry is the registry used to resolve keyring presets.
+	keyringRegistry *KeyringRegistry
+
+	// mirrorTable rewrites the image references the builder consumes, such as the apko image.
+	mirrorTable *containerx.MirrorTable
+
 	// buildArch specifies the architecture to build for.
 	buildArch string
 
+	// buildArchs is the set of architectures to build for in a single multi-arch build.
+	buildArchs []Architecture
+
 	// buildContext is the build context directory.
 	buildContext string
 
@@ -148,12 +165,64 @@ func (b *ApkoBuilder) WithBuildArch(arch Architecture) *ApkoBuilder {
 	return b
 }
 
+// WithBuildArchs adds a set of architectures to build for.
+// When more than one architecture is configured, BuildCommand emits a single multi-arch build
+// whose output tarball holds one image per architecture referenced from an OCI image index.
+// Duplicated architectures are ignored.
+// It returns the updated ApkoBuilder instance.
+func (b *ApkoBuilder) WithBuildArchs(archs ...Architecture) *ApkoBuilder {
+	for _, arch := range archs {
+		if !containsArch(b.buildArchs, arch) {
+			b.buildArchs = append(b.buildArchs, arch)
+		}
+	}
+
+	return b
+}
+
+// Architectures returns the architectures the builder targets, in the order they were configured.
+// The architecture set through WithBuildArch or WithArchitecture comes first, followed by the ones
+// added through WithBuildArchs.
+func (b *ApkoBuilder) Architectures() []Architecture {
+	var archs []Architecture
+	if b.buildArch != "" {
+		archs = append(archs, Architecture(b.buildArch))
+	}
+
+	for _, arch := range b.buildArchs {
+		if !containsArch(archs, arch) {
+			archs = append(archs, arch)
+		}
+	}
+
+	return archs
+}
+
 // NewApkoBuilder creates a new ApkoBuilder with default settings.
 // It initializes the ApkoBuilder with default architectures "x86_64" and "aarch64".
 func NewApkoBuilder() *ApkoBuilder {
 	return &ApkoBuilder{}
 }
 
+// clone returns a copy of the builder that shares no slices or maps with it.
+// The keyring registry and mirror table are shared, as they are not owned by the builder.
+func (b *ApkoBuilder) clone() *ApkoBuilder {
+	c := *b
+	c.keyringPaths = slices.Clone(b.keyringPaths)
+	c.extraArgs = slices.Clone(b.extraArgs)
+	c.keyringPresets = slices.Clone(b.keyringPresets)
+	c.buildArchs = slices.Clone(b.buildArchs)
+	c.keyringAppendPlaintext = slices.Clone(b.keyringAppendPlaintext)
+	c.repositoryAppend = slices.Clone(b.repositoryAppend)
+	c.tags = slices.Clone(b.tags)
+	c.annotations = maps.Clone(b.annotations)
+	c.packageAppend = slices.Clone(b.packageAppend)
+	c.sbomFormats = slices.Clone(b.sbomFormats)
+	c.logPolicy = slices.Clone(b.logPolicy)
+
+	return &c
+}
+
 // WithConfigFile sets the configuration file for the APKO build.
 // It takes a string parameter 'configFile' which is the path to the configuration file.
 // It returns the updated ApkoBuilder instance.
@@ -342,10 +411,18 @@ func (b *ApkoBuilder) WithWorkdir(dir string) *ApkoBuilder {
 	return b
 }
 
+// WithTags adds additional tags for the output image.
+// Each tag is emitted as an extra "--tag" image reference, alongside the primary tag set by WithTag.
+// It returns the updated ApkoBuilder instance.
+func (b *ApkoBuilder) WithTags(tags ...string) *ApkoBuilder {
+	b.tags = append(b.tags, tags...)
+	return b
+}
+
 // BuildCommand generates the APKO build command based on the current configuration of the ApkoBuilder.
+// Every configured option is translated into its apko flag; multi-value options are emitted as repeated
+// flags, in the order they were added. Annotations are emitted sorted by key so the command is deterministic.
 // It returns a slice of strings representing the command and an error if any required fields are missing.
-//
-//nolint:funlen // TODO: Refactor this function to make it more readable
 func (b *ApkoBuilder) BuildCommand() ([]string, error) {
 	if b.configFile == "" {
 		return nil, fmt.Errorf("config file is required")
@@ -359,6 +436,14 @@ func (b *ApkoBuilder) BuildCommand() ([]string, error) {
 		return nil, fmt.Errorf("output tarball path is required")
 	}
 
+	if err := b.validateArchs(); err != nil {
+		return nil, err
+	}
+
+	if _, err := b.resolveKeyringPresets(); err != nil {
+		return nil, err
+	}
+
 	// Default tag if not set
 	if b.tag == "" {
 		b.tag = "latest"
@@ -368,64 +453,211 @@ func (b *ApkoBuilder) BuildCommand() ([]string, error) {
 	cmd := []string{"apko", "build"}
 
 	// Add all flags before positional arguments
+	cmd = append(cmd, b.buildSourceFlags()...)
+	cmd = append(cmd, b.buildOutputFlags()...)
+
+	// Add the three required positional arguments last:
+	// 1. config file
+	// 2. image reference with tag
+	// 3. output path
+	imageRef := fmt.Sprintf("%s:%s", b.outputImage, b.tag)
+	cmd = append(cmd, b.configFile, imageRef, b.outputTarball)
+
+	// Add any extra arguments at the very end
+	cmd = append(cmd, b.extraArgs...)
+
+	return cmd, nil
+}
+
+// buildSourceFlags returns the flags that control where packages and keys come from:
+// cache, keyrings, architecture, repositories and network access.
+func (b *ApkoBuilder) buildSourceFlags() []string {
+	var flags []string
+
 	if b.cacheDir != "" {
-		cmd = append(cmd, "--cache-dir", b.cacheDir)
+		flags = append(flags, "--cache-dir", b.cacheDir)
 	}
 
-	for _, k := range b.keyringPaths {
-		cmd = append(cmd, "--keyring-append", k)
+	for _, k := range b.resolvedKeyringPaths() {
+		flags = append(flags, "--keyring-append", k)
 	}
 
-	if b.buildArch != "" {
-		cmd = append(cmd, "--arch", b.buildArch)
-	}
+	flags = append(flags, b.archFlags()...)
 
 	if b.buildContext != "" {
-		cmd = append(cmd, "--build-repository-append", b.buildContext)
+		flags = append(flags, "--build-repository-append", b.buildContext)
 	}
 
-	// Add other flags
 	if !b.sbom {
-		cmd = append(cmd, "--sbom=false")
+		flags = append(flags, "--sbom=false")
 	}
 
 	if !b.vcs {
-		cmd = append(cmd, "--vcs=false")
+		flags = append(flags, "--vcs=false")
 	}
 
-	// Add all other flags...
+	if b.debug {
+		flags = append(flags, "--debug")
+	}
 
-	// Add the three required positional arguments last:
-	// 1. config file
-	// 2. image reference with tag
-	// 3. output path
-	imageRef := fmt.Sprintf("%s:%s", b.outputImage, b.tag)
-	cmd = append(cmd, b.configFile, imageRef, b.outputTarball)
+	if b.offline {
+		flags = append(flags, "--offline")
+	}
 
-	// Add any extra arguments at the very end
-	cmd = append(cmd, b.extraArgs...)
+	if b.noNetwork {
+		flags = append(flags, "--no-network")
+	}
 
-	return cmd, nil
+	for _, k := range b.keyringAppendPlaintext {
+		flags = append(flags, "--keyring-append-plaintext", k)
+	}
+
+	for _, r := range b.repositoryAppend {
+		flags = append(flags, "--repository-append", r)
+	}
+
+	for _, p := range b.packageAppend {
+		flags = append(flags, "--package-append", p)
+	}
+
+	return flags
+}
+
+// buildOutputFlags returns the flags that control the produced image and its metadata:
+// annotations, dates, lockfile, SBOM, logging, working directory and additional tags.
+func (b *ApkoBuilder) buildOutputFlags() []string {
+	var flags []string
+
+	keys := make([]string, 0, len(b.annotations))
+	for k := range b.annotations {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		flags = append(flags, "--annotations", fmt.Sprintf("%s:%s", k, b.annotations[k]))
+	}
+
+	if b.buildDate != "" {
+		flags = append(flags, "--build-date", b.buildDate)
+	}
+
+	if b.timestamp != "" {
+		flags = append(flags, "--timestamp", b.timestamp)
+	}
+
+	if b.lockfile != "" {
+		flags = append(flags, "--lockfile", b.lockfile)
+	}
+
+	if b.sbomPath != "" {
+		flags = append(flags, "--sbom-path", b.sbomPath)
+	}
+
+	for _, f := range b.sbomFormats {
+		flags = append(flags, "--sbom-formats", f)
+	}
+
+	if b.logLevel != "" {
+		flags = append(flags, "--log-level", b.logLevel)
+	}
+
+	for _, p := range b.logPolicy {
+		flags = append(flags, "--log-policy", p)
+	}
+
+	if b.workdir != "" {
+		flags = append(flags, "--workdir", b.workdir)
+	}
+
+	for _, t := range b.tags {
+		flags = append(flags, "--tag", fmt.Sprintf("%s:%s", b.outputImage, t))
+	}
+
+	return flags
+}
+
+// validateArchs checks that every architecture added through WithBuildArchs is supported.
+func (b *ApkoBuilder) validateArchs() error {
+	for _, arch := range b.buildArchs {
+		if !arch.IsValid() {
+			return fmt.Errorf("unsupported architecture: %s", arch)
+		}
+	}
+
+	return nil
+}
+
+// archFlags returns the "--arch" flag with the comma-separated list of target architectures,
+// or nil if no architecture is configured.
+func (b *ApkoBuilder) archFlags() []string {
+	archs := b.Architectures()
+	if len(archs) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(archs))
+	for _, arch := range archs {
+		names = append(names, string(arch))
+	}
+
+	return []string{"--arch", strings.Join(names, ",")}
+}
+
+// sharedFlags returns the flags every apko subcommand accepts from the builder configuration:
+// cache directory, keyrings, architectures and appended repositories.
+func (b *ApkoBuilder) sharedFlags() []string {
+	var flags []string
+
+	if b.cacheDir != "" {
+		flags = append(flags, "--cache-dir", b.cacheDir)
+	}
+
+	for _, k := range b.resolvedKeyringPaths() {
+		flags = append(flags, "--keyring-append", k)
+	}
+
+	flags = append(flags, b.archFlags()...)
+
+	for _, r := range b.repositoryAppend {
+		flags = append(flags, "--repository-append", r)
+	}
+
+	return flags
+}
+
+// resolvedKeyringPaths returns the explicit keyring paths followed by the keyring presets
+// and the keyrings enabled through WithWolfiKeyring and WithAlpineKeyring, without duplicates.
+// Presets that cannot be resolved are skipped; callers validate them beforehand.
+func (b *ApkoBuilder) resolvedKeyringPaths() []string {
+	paths := append([]string(nil), b.keyringPaths...)
+
+	presetPaths, _ := b.resolveKeyringPresets()
+	paths = appendUnique(paths, presetPaths...)
+
+	if b.wolfiKeyring {
+		paths = appendUnique(paths, ApkoWolfiSigninRsaKeyPath)
+	}
+
+	if b.alpineKeyring {
+		paths = appendUnique(paths, ApkoAlpineSigninRsaKeyPath)
+	}
+
+	return paths
 }
 
 // GetKeyringInfoForPreset returns the keyring information based on the preset.
-// It takes a string parameter 'preset' which specifies the keyring preset ("alpine" or "wolfi").
+// It takes a string parameter 'preset' which specifies the keyring preset name registered
+// in the DefaultKeyringRegistry (e.g., "alpine" or "wolfi").
 // It returns a KeyringInfo struct and an error if the preset is unsupported.
 func GetKeyringInfoForPreset(preset string) (KeyringInfo, error) {
-	switch preset {
-	case "alpine":
-		return KeyringInfo{
-			KeyURL:  "https://alpinelinux.org/keys/alpine-devel@lists.alpinelinux.org-4a6a0840.rsa.pub",
-			KeyPath: "/etc/apk/keys/alpine-devel@lists.alpinelinux.org-4a6a0840.rsa.pub",
-		}, nil
-	case "wolfi":
-		return KeyringInfo{
-			KeyURL:  "https://packages.wolfi.dev/os/wolfi-signing.rsa.pub",
-			KeyPath: "/etc/apk/keys/wolfi-signing.rsa.pub",
-		}, nil
-	default:
-		return KeyringInfo{}, fmt.Errorf("unsupported preset: %s", preset)
+	p, err := DefaultKeyringRegistry.Lookup(preset)
+	if err != nil {
+		return KeyringInfo{}, err
 	}
+
+	return p.KeyringInfo(), nil
 }
 
 // GetCacheDir returns the APKO cache directory path.
@@ -479,21 +711,26 @@ func GetOutputTarPath(mntPrefix string) string {
 // It appends the Wolfi signing key to the keyringPaths.
 // Returns the updated ApkoBuilder instance.
 func (b *ApkoBuilder) WithKeyRingWolfi() *ApkoBuilder {
-	wolfiKeyInfo, err := GetKeyringInfoForPreset("wolfi")
-	if err == nil {
-		b.keyringPaths = append(b.keyringPaths, wolfiKeyInfo.KeyPath)
-	}
-	return b
+	return b.withKeyringPresetPath(KeyringPresetWolfi)
 }
 
 // WithKeyRingAlpine adds the Alpine keyring to the APKO build.
 // It appends the Alpine signing key to the keyringPaths.
 // Returns the updated ApkoBuilder instance.
 func (b *ApkoBuilder) WithKeyRingAlpine() *ApkoBuilder {
-	alpineKeyInfo, err := GetKeyringInfoForPreset("alpine")
-	if err == nil {
-		b.keyringPaths = append(b.keyringPaths, alpineKeyInfo.KeyPath)
+	return b.withKeyringPresetPath(KeyringPresetAlpine)
+}
+
+// withKeyringPresetPath appends the key path of the given preset to the keyringPaths.
+// If the preset cannot be resolved, it is kept as a pending preset so BuildCommand reports the error.
+func (b *ApkoBuilder) withKeyringPresetPath(name string) *ApkoBuilder {
+	preset, err := b.registry().Lookup(name)
+	if err != nil {
+		return b.WithKeyringPreset(name)
 	}
+
+	b.keyringPaths = append(b.keyringPaths, preset.KeyPath)
+
 	return b
 }
 
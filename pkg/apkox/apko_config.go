@@ -0,0 +1,425 @@
+package apkox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/Excoriate/daggerx/pkg/filex"
+	"gopkg.in/yaml.v3"
+)
+
+// ApkoConfig is the Go representation of an apko image configuration file.
+// It mirrors the subset of the apko YAML schema that is commonly used to compose images,
+// so configurations can be loaded, merged, validated and rendered back to YAML
+// instead of being templated as raw strings.
+type ApkoConfig struct {
+	// Contents describes the repositories, keyrings and packages installed in the image.
+	Contents ApkoContents `yaml:"contents"`
+	// Entrypoint is the command the image runs by default.
+	Entrypoint ApkoEntrypoint `yaml:"entrypoint,omitempty"`
+	// Cmd holds the default arguments passed to the entrypoint.
+	Cmd string `yaml:"cmd,omitempty"`
+	// WorkDir is the working directory of the image.
+	WorkDir string `yaml:"work-dir,omitempty"`
+	// Accounts configures users, groups and the user the image runs as.
+	Accounts ApkoAccounts `yaml:"accounts,omitempty"`
+	// Environment holds the environment variables set in the image.
+	Environment map[string]string `yaml:"environment,omitempty"`
+	// Paths lists filesystem mutations applied to the image.
+	Paths []ApkoPath `yaml:"paths,omitempty"`
+	// Archs lists the architectures the image is built for.
+	Archs []Architecture `yaml:"archs,omitempty"`
+	// Annotations holds the OCI annotations added to the image.
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// ApkoContents describes the package sources and packages of an apko image.
+type ApkoContents struct {
+	// Repositories is the list of APK repositories to fetch packages from.
+	Repositories []string `yaml:"repositories,omitempty"`
+	// Keyring is the list of keys (paths or URLs) used to verify packages.
+	Keyring []string `yaml:"keyring,omitempty"`
+	// Packages is the list of packages, optionally with version constraints, to install.
+	Packages []string `yaml:"packages,omitempty"`
+}
+
+// ApkoEntrypoint describes the default command of an apko image.
+type ApkoEntrypoint struct {
+	// Type is the entrypoint type (e.g., "service-bundle"). Empty means a plain command.
+	Type string `yaml:"type,omitempty"`
+	// Command is the command executed when the container starts.
+	Command string `yaml:"command,omitempty"`
+	// Services maps service names to commands when Type is "service-bundle".
+	Services map[string]string `yaml:"services,omitempty"`
+}
+
+// ApkoAccounts describes the users and groups created in an apko image.
+type ApkoAccounts struct {
+	// RunAs is the user (name or UID) the image runs as.
+	RunAs string `yaml:"run-as,omitempty"`
+	// Users is the list of users to create.
+	Users []ApkoUser `yaml:"users,omitempty"`
+	// Groups is the list of groups to create.
+	Groups []ApkoGroup `yaml:"groups,omitempty"`
+}
+
+// ApkoUser describes a user account created in an apko image.
+type ApkoUser struct {
+	// UserName is the name of the user.
+	UserName string `yaml:"username"`
+	// UID is the numeric user ID.
+	UID uint32 `yaml:"uid"`
+	// GID is the numeric primary group ID.
+	GID uint32 `yaml:"gid,omitempty"`
+	// Shell is the login shell of the user.
+	Shell string `yaml:"shell,omitempty"`
+	// HomeDir is the home directory of the user.
+	HomeDir string `yaml:"homedir,omitempty"`
+}
+
+// ApkoGroup describes a group created in an apko image.
+type ApkoGroup struct {
+	// GroupName is the name of the group.
+	GroupName string `yaml:"groupname"`
+	// GID is the numeric group ID.
+	GID uint32 `yaml:"gid"`
+	// Members lists the user names belonging to the group.
+	Members []string `yaml:"members,omitempty"`
+}
+
+// ApkoPath describes a filesystem mutation applied to an apko image.
+type ApkoPath struct {
+	// Path is the absolute path inside the image.
+	Path string `yaml:"path"`
+	// Type is the mutation type (e.g., "directory", "empty-file", "hardlink", "symlink", "permissions").
+	Type string `yaml:"type"`
+	// UID is the owner user ID of the path.
+	UID uint32 `yaml:"uid,omitempty"`
+	// GID is the owner group ID of the path.
+	GID uint32 `yaml:"gid,omitempty"`
+	// Permissions is the octal file mode of the path.
+	Permissions uint32 `yaml:"permissions,omitempty"`
+	// Source is the link target for "hardlink" and "symlink" paths.
+	Source string `yaml:"source,omitempty"`
+	// Recursive applies the mutation to every path under Path.
+	Recursive bool `yaml:"recursive,omitempty"`
+}
+
+// validPathTypes holds the path mutation types accepted by apko.
+var validPathTypes = map[string]bool{
+	"directory":   true,
+	"empty-file":  true,
+	"hardlink":    true,
+	"symlink":     true,
+	"permissions": true,
+}
+
+// SupportedArchitectures returns the list of architectures supported by APKO builds.
+func SupportedArchitectures() []Architecture {
+	return []Architecture{ArchX8664, ArchAarch64, ArchArmv7, ArchPpc64le, ArchS390x}
+}
+
+// IsValid reports whether the architecture is one of the architectures supported by APKO builds.
+func (a Architecture) IsValid() bool {
+	for _, supported := range SupportedArchitectures() {
+		if a == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseApkoConfig parses the given YAML content into an ApkoConfig.
+// It returns an error if the content is empty or is not a valid apko configuration document.
+func ParseApkoConfig(data []byte) (*ApkoConfig, error) {
+	if len(data) == 0 {
+		return nil, errors.New("apko config content is empty")
+	}
+
+	var cfg ApkoConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse apko config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// LoadApkoConfig reads and parses the apko configuration file at the given path.
+// The file must have a .yaml or .yml extension, exist, and have content.
+//
+// Example:
+//
+//	cfg, err := LoadApkoConfig("apko.yaml")
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(cfg.Contents.Packages)
+func LoadApkoConfig(path string) (*ApkoConfig, error) {
+	var cfg ApkoConfig
+	if err := filex.ValidateYAML(path, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to load apko config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// MergeApkoConfigs merges several configuration fragments into a single ApkoConfig.
+// Fragments are applied in order: list fields are appended (skipping duplicates),
+// map fields are merged with later fragments overriding earlier keys, users and groups are
+// merged by name with later fragments replacing earlier definitions in place, and scalar
+// fields are overridden by later fragments when they are not empty. Nil fragments are ignored.
+//
+// Example:
+//
+//	base := &ApkoConfig{Contents: ApkoContents{Packages: []string{"wolfi-base"}}}
+//	extra := &ApkoConfig{Contents: ApkoContents{Packages: []string{"curl"}}}
+//	cfg := MergeApkoConfigs(base, extra)
+//	fmt.Println(cfg.Contents.Packages) // Output: [wolfi-base curl]
+func MergeApkoConfigs(configs ...*ApkoConfig) *ApkoConfig {
+	merged := &ApkoConfig{}
+
+	for _, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+
+		merged.Contents.Repositories = appendUnique(merged.Contents.Repositories, cfg.Contents.Repositories...)
+		merged.Contents.Keyring = appendUnique(merged.Contents.Keyring, cfg.Contents.Keyring...)
+		merged.Contents.Packages = appendUnique(merged.Contents.Packages, cfg.Contents.Packages...)
+
+		if cfg.Entrypoint.Type != "" {
+			merged.Entrypoint.Type = cfg.Entrypoint.Type
+		}
+
+		if cfg.Entrypoint.Command != "" {
+			merged.Entrypoint.Command = cfg.Entrypoint.Command
+		}
+
+		merged.Entrypoint.Services = mergeStringMaps(merged.Entrypoint.Services, cfg.Entrypoint.Services)
+
+		if cfg.Cmd != "" {
+			merged.Cmd = cfg.Cmd
+		}
+
+		if cfg.WorkDir != "" {
+			merged.WorkDir = cfg.WorkDir
+		}
+
+		if cfg.Accounts.RunAs != "" {
+			merged.Accounts.RunAs = cfg.Accounts.RunAs
+		}
+
+		merged.Accounts.Users = mergeUsers(merged.Accounts.Users, cfg.Accounts.Users)
+		merged.Accounts.Groups = mergeGroups(merged.Accounts.Groups, cfg.Accounts.Groups)
+		merged.Environment = mergeStringMaps(merged.Environment, cfg.Environment)
+		merged.Paths = append(merged.Paths, cfg.Paths...)
+
+		for _, arch := range cfg.Archs {
+			if !containsArch(merged.Archs, arch) {
+				merged.Archs = append(merged.Archs, arch)
+			}
+		}
+
+		merged.Annotations = mergeStringMaps(merged.Annotations, cfg.Annotations)
+	}
+
+	return merged
+}
+
+// Validate checks that the configuration can be consumed by apko.
+// It requires at least one repository and one package, validates the keyring entries,
+// the architectures, the path mutations and the uniqueness of users and groups.
+//
+//nolint:cyclop // Each check is a flat, independent validation rule.
+func (c *ApkoConfig) Validate() error {
+	if len(c.Contents.Repositories) == 0 {
+		return errors.New("at least one repository is required in contents.repositories")
+	}
+
+	if len(c.Contents.Packages) == 0 {
+		return errors.New("at least one package is required in contents.packages")
+	}
+
+	for _, pkg := range c.Contents.Packages {
+		if pkg == "" {
+			return errors.New("package name cannot be empty")
+		}
+	}
+
+	for _, key := range c.Contents.Keyring {
+		if key == "" {
+			return errors.New("keyring entry cannot be empty")
+		}
+	}
+
+	for _, arch := range c.Archs {
+		if !arch.IsValid() {
+			return fmt.Errorf("unsupported architecture: %s", arch)
+		}
+	}
+
+	users := make(map[string]bool)
+	for _, user := range c.Accounts.Users {
+		if user.UserName == "" {
+			return errors.New("user name cannot be empty")
+		}
+
+		if users[user.UserName] {
+			return fmt.Errorf("duplicate user: %s", user.UserName)
+		}
+
+		users[user.UserName] = true
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range c.Accounts.Groups {
+		if group.GroupName == "" {
+			return errors.New("group name cannot be empty")
+		}
+
+		if groups[group.GroupName] {
+			return fmt.Errorf("duplicate group: %s", group.GroupName)
+		}
+
+		groups[group.GroupName] = true
+	}
+
+	for _, p := range c.Paths {
+		if !filepath.IsAbs(p.Path) {
+			return fmt.Errorf("path must be absolute: %s", p.Path)
+		}
+
+		if !validPathTypes[p.Type] {
+			return fmt.Errorf("invalid path type %q for %s", p.Type, p.Path)
+		}
+
+		if (p.Type == "hardlink" || p.Type == "symlink") && p.Source == "" {
+			return fmt.Errorf("path %s of type %s requires a source", p.Path, p.Type)
+		}
+	}
+
+	return nil
+}
+
+// Render validates the configuration and renders it as apko YAML.
+func (c *ApkoConfig) Render() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid apko config: %w", err)
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render apko config: %w", err)
+	}
+
+	return data, nil
+}
+
+// WriteFile validates and renders the configuration to the given path, so it can be passed
+// to ApkoBuilder.WithConfigFile. The path must have a .yaml or .yml extension.
+//
+// Example:
+//
+//	if err := cfg.WriteFile("/tmp/apko.yaml"); err != nil {
+//	    // handle error
+//	}
+//	builder := NewApkoBuilder().WithConfigFile("/tmp/apko.yaml")
+func (c *ApkoConfig) WriteFile(path string) error {
+	if !filex.ValidateYAMLExtension(path) {
+		return fmt.Errorf("config file must have a .yaml or .yml extension: %s", path)
+	}
+
+	data, err := c.Render()
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // The rendered configuration is not sensitive and must be readable by apko.
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write apko config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// appendUnique appends the values that are not already present in the slice.
+func appendUnique(dst []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+
+		for _, existing := range dst {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			dst = append(dst, v)
+		}
+	}
+
+	return dst
+}
+
+// mergeUsers appends the users to dst, replacing in place the users with the same name.
+func mergeUsers(dst, users []ApkoUser) []ApkoUser {
+	for _, user := range users {
+		i := slices.IndexFunc(dst, func(u ApkoUser) bool { return u.UserName == user.UserName })
+		if i < 0 {
+			dst = append(dst, user)
+			continue
+		}
+
+		dst[i] = user
+	}
+
+	return dst
+}
+
+// mergeGroups appends the groups to dst, replacing in place the groups with the same name.
+func mergeGroups(dst, groups []ApkoGroup) []ApkoGroup {
+	for _, group := range groups {
+		i := slices.IndexFunc(dst, func(g ApkoGroup) bool { return g.GroupName == group.GroupName })
+		if i < 0 {
+			dst = append(dst, group)
+			continue
+		}
+
+		dst[i] = group
+	}
+
+	return dst
+}
+
+// mergeStringMaps copies the entries of src into dst, overriding existing keys.
+func mergeStringMaps(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+
+	for k, v := range src {
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// containsArch reports whether the architecture is present in the slice.
+func containsArch(archs []Architecture, arch Architecture) bool {
+	for _, a := range archs {
+		if a == arch {
+			return true
+		}
+	}
+
+	return false
+}
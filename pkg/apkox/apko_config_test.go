@@ -0,0 +1,278 @@
+package apkox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testApkoConfigYAML = `contents:
+  repositories:
+    - https://packages.wolfi.dev/os
+  keyring:
+    - https://packages.wolfi.dev/os/wolfi-signing.rsa.pub
+  packages:
+    - wolfi-base
+    - ca-certificates-bundle
+entrypoint:
+  command: /bin/sh -l
+accounts:
+  run-as: nonroot
+  users:
+    - username: nonroot
+      uid: 65532
+  groups:
+    - groupname: nonroot
+      gid: 65532
+environment:
+  PATH: /usr/sbin:/sbin:/usr/bin:/bin
+paths:
+  - path: /app
+    type: directory
+    uid: 65532
+    gid: 65532
+    permissions: 0o755
+archs:
+  - x86_64
+  - aarch64
+annotations:
+  org.opencontainers.image.source: https://github.com/Excoriate/daggerx
+`
+
+func TestParseApkoConfig(t *testing.T) {
+	t.Run("Valid config", func(t *testing.T) {
+		cfg, err := ParseApkoConfig([]byte(testApkoConfigYAML))
+		if err != nil {
+			t.Fatalf("ParseApkoConfig() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(cfg.Contents.Packages, []string{"wolfi-base", "ca-certificates-bundle"}) {
+			t.Errorf("Packages = %v", cfg.Contents.Packages)
+		}
+
+		if cfg.Entrypoint.Command != "/bin/sh -l" {
+			t.Errorf("Entrypoint.Command = %s", cfg.Entrypoint.Command)
+		}
+
+		if cfg.Accounts.RunAs != "nonroot" || len(cfg.Accounts.Users) != 1 || cfg.Accounts.Users[0].UID != 65532 {
+			t.Errorf("Accounts = %+v", cfg.Accounts)
+		}
+
+		if cfg.Paths[0].Permissions != 0o755 {
+			t.Errorf("Paths[0].Permissions = %o, want 755", cfg.Paths[0].Permissions)
+		}
+
+		if !reflect.DeepEqual(cfg.Archs, []Architecture{ArchX8664, ArchAarch64}) {
+			t.Errorf("Archs = %v", cfg.Archs)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	})
+
+	t.Run("Empty content", func(t *testing.T) {
+		if _, err := ParseApkoConfig(nil); err == nil {
+			t.Error("ParseApkoConfig() expected error for empty content")
+		}
+	})
+
+	t.Run("Invalid YAML", func(t *testing.T) {
+		if _, err := ParseApkoConfig([]byte("contents: [")); err == nil {
+			t.Error("ParseApkoConfig() expected error for invalid YAML")
+		}
+	})
+}
+
+func TestLoadApkoConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "apko.yaml")
+	if err := os.WriteFile(valid, []byte(testApkoConfigYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadApkoConfig(valid)
+	if err != nil {
+		t.Fatalf("LoadApkoConfig() error = %v", err)
+	}
+
+	if len(cfg.Contents.Repositories) != 1 {
+		t.Errorf("Repositories = %v", cfg.Contents.Repositories)
+	}
+
+	if _, err := LoadApkoConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadApkoConfig() expected error for missing file")
+	}
+
+	if _, err := LoadApkoConfig(filepath.Join(dir, "apko.json")); err == nil {
+		t.Error("LoadApkoConfig() expected error for invalid extension")
+	}
+}
+
+func TestMergeApkoConfigs(t *testing.T) {
+	base := &ApkoConfig{
+		Contents: ApkoContents{
+			Repositories: []string{"https://packages.wolfi.dev/os"},
+			Packages:     []string{"wolfi-base"},
+		},
+		Entrypoint:  ApkoEntrypoint{Command: "/bin/sh"},
+		Environment: map[string]string{"FOO": "base", "BAR": "base"},
+		Archs:       []Architecture{ArchX8664},
+	}
+
+	extra := &ApkoConfig{
+		Contents: ApkoContents{
+			Repositories: []string{"https://packages.wolfi.dev/os"},
+			Packages:     []string{"curl", "wolfi-base"},
+		},
+		Entrypoint:  ApkoEntrypoint{Command: "/usr/bin/curl"},
+		Environment: map[string]string{"FOO": "extra"},
+		Archs:       []Architecture{ArchAarch64, ArchX8664},
+		Annotations: map[string]string{"key": "value"},
+	}
+
+	got := MergeApkoConfigs(base, nil, extra)
+
+	want := &ApkoConfig{
+		Contents: ApkoContents{
+			Repositories: []string{"https://packages.wolfi.dev/os"},
+			Packages:     []string{"wolfi-base", "curl"},
+		},
+		Entrypoint:  ApkoEntrypoint{Command: "/usr/bin/curl"},
+		Environment: map[string]string{"FOO": "extra", "BAR": "base"},
+		Archs:       []Architecture{ArchX8664, ArchAarch64},
+		Annotations: map[string]string{"key": "value"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeApkoConfigs() = %+v, want %+v", got, want)
+	}
+
+	if base.Environment["FOO"] != "base" {
+		t.Error("MergeApkoConfigs() must not mutate its inputs")
+	}
+}
+
+func TestMergeApkoConfigsAccounts(t *testing.T) {
+	base := &ApkoConfig{
+		Accounts: ApkoAccounts{
+			Users:  []ApkoUser{{UserName: "nonroot", UID: 65532}, {UserName: "app", UID: 1000}},
+			Groups: []ApkoGroup{{GroupName: "nonroot", GID: 65532}},
+		},
+	}
+
+	extra := &ApkoConfig{
+		Accounts: ApkoAccounts{
+			Users:  []ApkoUser{{UserName: "nonroot", UID: 65532, HomeDir: "/home/nonroot"}},
+			Groups: []ApkoGroup{{GroupName: "nonroot", GID: 65532, Members: []string{"nonroot"}}},
+		},
+	}
+
+	got := MergeApkoConfigs(base, extra)
+
+	wantUsers := []ApkoUser{{UserName: "nonroot", UID: 65532, HomeDir: "/home/nonroot"}, {UserName: "app", UID: 1000}}
+	if !reflect.DeepEqual(got.Accounts.Users, wantUsers) {
+		t.Errorf("Users = %+v, want %+v", got.Accounts.Users, wantUsers)
+	}
+
+	wantGroups := []ApkoGroup{{GroupName: "nonroot", GID: 65532, Members: []string{"nonroot"}}}
+	if !reflect.DeepEqual(got.Accounts.Groups, wantGroups) {
+		t.Errorf("Groups = %+v, want %+v", got.Accounts.Groups, wantGroups)
+	}
+
+	got.Contents = ApkoContents{Repositories: []string{"https://packages.wolfi.dev/os"}, Packages: []string{"wolfi-base"}}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	if base.Accounts.Users[0].HomeDir != "" {
+		t.Error("MergeApkoConfigs() must not mutate its inputs")
+	}
+}
+
+func TestApkoConfigValidate(t *testing.T) {
+	valid := func() *ApkoConfig {
+		return &ApkoConfig{
+			Contents: ApkoContents{
+				Repositories: []string{"https://packages.wolfi.dev/os"},
+				Packages:     []string{"wolfi-base"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(c *ApkoConfig)
+		wantErr bool
+	}{
+		{name: "Valid", mutate: func(_ *ApkoConfig) {}},
+		{name: "No repositories", mutate: func(c *ApkoConfig) { c.Contents.Repositories = nil }, wantErr: true},
+		{name: "No packages", mutate: func(c *ApkoConfig) { c.Contents.Packages = nil }, wantErr: true},
+		{name: "Empty keyring entry", mutate: func(c *ApkoConfig) { c.Contents.Keyring = []string{""} }, wantErr: true},
+		{name: "Unsupported arch", mutate: func(c *ApkoConfig) { c.Archs = []Architecture{"mips"} }, wantErr: true},
+		{
+			name: "Duplicate user",
+			mutate: func(c *ApkoConfig) {
+				c.Accounts.Users = []ApkoUser{{UserName: "a", UID: 1}, {UserName: "a", UID: 2}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Relative path",
+			mutate:  func(c *ApkoConfig) { c.Paths = []ApkoPath{{Path: "app", Type: "directory"}} },
+			wantErr: true,
+		},
+		{
+			name:    "Invalid path type",
+			mutate:  func(c *ApkoConfig) { c.Paths = []ApkoPath{{Path: "/app", Type: "fifo"}} },
+			wantErr: true,
+		},
+		{
+			name:    "Symlink without source",
+			mutate:  func(c *ApkoConfig) { c.Paths = []ApkoPath{{Path: "/app", Type: "symlink"}} },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(cfg)
+
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApkoConfigWriteFile(t *testing.T) {
+	cfg, err := ParseApkoConfig([]byte(testApkoConfigYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "rendered.yaml")
+	if err := cfg.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	roundTrip, err := LoadApkoConfig(path)
+	if err != nil {
+		t.Fatalf("LoadApkoConfig() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg, roundTrip) {
+		t.Errorf("round trip mismatch: got %+v, want %+v", roundTrip, cfg)
+	}
+
+	if err := cfg.WriteFile(filepath.Join(t.TempDir(), "rendered.txt")); err == nil {
+		t.Error("WriteFile() expected error for invalid extension")
+	}
+
+	if err := (&ApkoConfig{}).WriteFile(path); err == nil {
+		t.Error("WriteFile() expected error for invalid config")
+	}
+}
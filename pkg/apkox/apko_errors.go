@@ -0,0 +1,260 @@
+package apkox
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ApkoErrorKind identifies the class of an apko build failure.
+type ApkoErrorKind string
+
+const (
+	// ApkoErrorUnknownPackage means a requested package does not exist in the configured repositories.
+	ApkoErrorUnknownPackage ApkoErrorKind = "unknown-package"
+	// ApkoErrorUnsatisfiableConstraint means a package exists but no version satisfies the constraints.
+	ApkoErrorUnsatisfiableConstraint ApkoErrorKind = "unsatisfiable-constraint"
+	// ApkoErrorSignature means a package or index signature could not be verified with the keyring.
+	ApkoErrorSignature ApkoErrorKind = "signature"
+	// ApkoErrorRepositoryUnreachable means a repository could not be reached. It is usually transient.
+	ApkoErrorRepositoryUnreachable ApkoErrorKind = "repository-unreachable"
+	// ApkoErrorInvalidConfig means the apko configuration could not be loaded or is invalid.
+	ApkoErrorInvalidConfig ApkoErrorKind = "invalid-config"
+	// ApkoErrorUnknown means the failure could not be classified.
+	ApkoErrorUnknown ApkoErrorKind = "unknown"
+)
+
+var (
+	// ErrApkoUnknownPackage is matched by errors.Is for ApkoErrorUnknownPackage failures.
+	ErrApkoUnknownPackage = errors.New("apko: unknown package")
+	// ErrApkoUnsatisfiableConstraint is matched by errors.Is for ApkoErrorUnsatisfiableConstraint failures.
+	ErrApkoUnsatisfiableConstraint = errors.New("apko: unsatisfiable constraint")
+	// ErrApkoSignature is matched by errors.Is for ApkoErrorSignature failures.
+	ErrApkoSignature = errors.New("apko: signature verification failed")
+	// ErrApkoRepositoryUnreachable is matched by errors.Is for ApkoErrorRepositoryUnreachable failures.
+	ErrApkoRepositoryUnreachable = errors.New("apko: repository unreachable")
+	// ErrApkoInvalidConfig is matched by errors.Is for ApkoErrorInvalidConfig failures.
+	ErrApkoInvalidConfig = errors.New("apko: invalid configuration")
+	// ErrApkoUnknown is matched by errors.Is for failures that could not be classified.
+	ErrApkoUnknown = errors.New("apko: build failed")
+)
+
+// ApkoBuildError is a classified apko build failure.
+type ApkoBuildError struct {
+	// Kind is the class of the failure.
+	Kind ApkoErrorKind
+	// Package is the offending package or constraint, if any.
+	Package string
+	// Repository is the offending repository, if any.
+	Repository string
+	// Message is the output line the failure was classified from.
+	Message string
+	// Output is the complete apko output.
+	Output string
+}
+
+// apkoErrorRule classifies apko output lines matching a pattern into an error kind.
+type apkoErrorRule struct {
+	kind    ApkoErrorKind
+	pattern *regexp.Regexp
+}
+
+// apkoErrorRules are evaluated in order against a line; the first matching rule wins. Network and signature
+// failures are checked before package failures because their messages also mention packages and indexes.
+// Patterns match whole phrases, so that package names such as "py3-signature" do not match them.
+var apkoErrorRules = []apkoErrorRule{
+	{
+		kind: ApkoErrorInvalidConfig,
+		pattern: regexp.MustCompile(`(?i)(failed to load (image )?configuration|yaml: (unmarshal|line \d+)|` +
+			`invalid (image )?config|unknown field|configuration file .* not found)`),
+	},
+	{
+		kind: ApkoErrorRepositoryUnreachable,
+		pattern: regexp.MustCompile(`(?i)(connection refused|connection reset|no such host|i/o timeout|` +
+			`timeout awaiting|context deadline exceeded|tls handshake|temporary failure|network is unreachable|` +
+			`unexpected status code:? (429|5\d\d)|\b(429|502|503|504) (too many requests|bad gateway|` +
+			`service unavailable|gateway timeout))`),
+	},
+	{
+		kind: ApkoErrorSignature,
+		pattern: regexp.MustCompile(`(?i)(failed to verify (the )?signature|signature verification failed|` +
+			`(bad|invalid|untrusted) signature|no key found|untrusted (public )?key|` +
+			`unable to verify signature|rsa verification (error|failed))`),
+	},
+	{
+		kind: ApkoErrorUnsatisfiableConstraint,
+		pattern: regexp.MustCompile(`(?i)(could not find constraint|unsatisfiable|conflicts with|` +
+			`breaks:|no version of|version .* not found)`),
+	},
+	{
+		kind: ApkoErrorUnknownPackage,
+		pattern: regexp.MustCompile(`(?i)(could not find package|nothing provides|package .* not found|` +
+			`unknown package|no such package)`),
+	},
+}
+
+var (
+	// apkoQuotedNameRegex extracts the quoted package or constraint name of an apko message.
+	apkoQuotedNameRegex = regexp.MustCompile(`(?:could not find (?:package|constraint)|nothing provides|` +
+		`package|solving|constraint) "([^"]+)"`)
+	// apkoBareNameRegex extracts an unquoted package name of an apko message.
+	apkoBareNameRegex = regexp.MustCompile(`(?:could not find package|nothing provides|unknown package|` +
+		`no such package) ([^\s"]+)`)
+	// apkoRepositoryRegex extracts the repository of an APKINDEX URL.
+	apkoRepositoryRegex = regexp.MustCompile(`(https?://[^\s"']+?)(?:/(?:x86_64|aarch64|armv7|ppc64le|s390x))?/APKINDEX\.tar\.gz`)
+	// apkoURLRegex extracts any URL of an apko message.
+	apkoURLRegex = regexp.MustCompile(`https?://[^\s"']+`)
+	// apkoErrorLineRegex matches the output lines reporting an error, as opposed to informational logs.
+	apkoErrorLineRegex = regexp.MustCompile(`(?i)\b(error|fatal|failed)\b`)
+)
+
+// Error returns a human-readable description of the failure. It is safe to call on a nil error.
+func (e *ApkoBuildError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+
+	var details []string
+	if e.Package != "" {
+		details = append(details, fmt.Sprintf("package %q", e.Package))
+	}
+
+	if e.Repository != "" {
+		details = append(details, fmt.Sprintf("repository %q", e.Repository))
+	}
+
+	msg := e.sentinel().Error()
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	return msg
+}
+
+// Unwrap returns the sentinel error of the failure kind, so errors.Is(err, ErrApkoSignature) works.
+func (e *ApkoBuildError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+
+	return e.sentinel()
+}
+
+// Transient reports whether the failure is likely to succeed on retry. A nil error is not transient.
+func (e *ApkoBuildError) Transient() bool {
+	return e != nil && e.Kind == ApkoErrorRepositoryUnreachable
+}
+
+// sentinel returns the sentinel error of the failure kind.
+func (e *ApkoBuildError) sentinel() error {
+	switch e.Kind {
+	case ApkoErrorUnknownPackage:
+		return ErrApkoUnknownPackage
+	case ApkoErrorUnsatisfiableConstraint:
+		return ErrApkoUnsatisfiableConstraint
+	case ApkoErrorSignature:
+		return ErrApkoSignature
+	case ApkoErrorRepositoryUnreachable:
+		return ErrApkoRepositoryUnreachable
+	case ApkoErrorInvalidConfig:
+		return ErrApkoInvalidConfig
+	default:
+		return ErrApkoUnknown
+	}
+}
+
+// ClassifyApkoError classifies the output (usually stderr) of a failed apko command into an ApkoBuildError,
+// extracting the offending package or repository when present.
+// Lines are classified one at a time, from the last to the first, since apko prints the root cause last:
+// error lines are tried first, then the other lines, and the first line matching a failure class wins.
+// Within a line, failure classes are checked in priority order.
+// It never returns nil: empty output, or output that matches no known failure, is classified as ApkoErrorUnknown.
+//
+// Example:
+//
+//	if _, err := ctr.Stdout(ctx); err != nil {
+//	    var execErr *dagger.ExecError
+//	    if errors.As(err, &execErr) {
+//	        buildErr := ClassifyApkoError(execErr.Stderr)
+//	        if buildErr.Transient() {
+//	            // retry
+//	        }
+//	        return buildErr
+//	    }
+//	}
+func ClassifyApkoError(output string) *ApkoBuildError {
+	if strings.TrimSpace(output) == "" {
+		return &ApkoBuildError{Kind: ApkoErrorUnknown, Output: output}
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	for _, errorLines := range []bool{true, false} {
+		for i := len(lines) - 1; i >= 0; i-- {
+			line := strings.TrimSpace(lines[i])
+			if apkoErrorLineRegex.MatchString(line) != errorLines {
+				continue
+			}
+
+			if kind, ok := classifyApkoLine(line); ok {
+				return newApkoBuildError(kind, line, output)
+			}
+		}
+	}
+
+	return &ApkoBuildError{Kind: ApkoErrorUnknown, Message: lastNonEmptyLine(lines), Output: output}
+}
+
+// IsTransientApkoError reports whether the error is an ApkoBuildError that is likely to succeed on retry.
+func IsTransientApkoError(err error) bool {
+	var buildErr *ApkoBuildError
+	return errors.As(err, &buildErr) && buildErr.Transient()
+}
+
+// classifyApkoLine returns the kind of the first rule matching the line.
+func classifyApkoLine(line string) (ApkoErrorKind, bool) {
+	for _, rule := range apkoErrorRules {
+		if rule.pattern.MatchString(line) {
+			return rule.kind, true
+		}
+	}
+
+	return ApkoErrorUnknown, false
+}
+
+// newApkoBuildError creates a classified error and extracts its package and repository from the line.
+func newApkoBuildError(kind ApkoErrorKind, line, output string) *ApkoBuildError {
+	buildErr := &ApkoBuildError{Kind: kind, Message: line, Output: output}
+
+	if kind == ApkoErrorUnknownPackage || kind == ApkoErrorUnsatisfiableConstraint {
+		if m := apkoQuotedNameRegex.FindAllStringSubmatch(line, -1); len(m) > 0 {
+			buildErr.Package = m[len(m)-1][1]
+		} else if m := apkoBareNameRegex.FindStringSubmatch(line); m != nil {
+			buildErr.Package = strings.TrimRight(m[1], ".,:;")
+		}
+	}
+
+	if m := apkoRepositoryRegex.FindStringSubmatch(line); m != nil {
+		buildErr.Repository = m[1]
+	} else if kind == ApkoErrorRepositoryUnreachable || kind == ApkoErrorSignature {
+		buildErr.Repository = strings.TrimRight(apkoURLRegex.FindString(line), ".,:;)")
+	}
+
+	return buildErr
+}
+
+// lastNonEmptyLine returns the last line that is not blank.
+func lastNonEmptyLine(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,209 @@
+package apkox
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyApkoError(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		kind       ApkoErrorKind
+		pkg        string
+		repository string
+		sentinel   error
+		transient  bool
+	}{
+		{
+			name: "Unknown package",
+			output: `2024/01/01 12:00:00 INFO loading config file: apko.yaml
+Error: failed to build layer image for "amd64": initializing apk: failed to install packages: ` +
+				`solving "does-not-exist" constraint: could not find package "does-not-exist" in indexes`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "does-not-exist",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name:     "Nothing provides",
+			output:   `Error: solving "curl" constraint: nothing provides "so:libfoo.so.1"`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "so:libfoo.so.1",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name:     "Unquoted package name",
+			output:   `ERROR: unable to select packages: nothing provides libbar in indexes`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "libbar",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name:     "Unsatisfiable constraint",
+			output:   `Error: failed to install packages: could not find constraint "glibc=2.99-r0" in indexes`,
+			kind:     ApkoErrorUnsatisfiableConstraint,
+			pkg:      "glibc=2.99-r0",
+			sentinel: ErrApkoUnsatisfiableConstraint,
+		},
+		{
+			name: "Signature failure",
+			output: `Error: failed to get package indexes: ` +
+				`failed to verify signature for https://packages.wolfi.dev/os/x86_64/APKINDEX.tar.gz: no key found`,
+			kind:       ApkoErrorSignature,
+			repository: "https://packages.wolfi.dev/os",
+			sentinel:   ErrApkoSignature,
+		},
+		{
+			name: "Repository unreachable",
+			output: `Error: GET https://apk.example.com/os/aarch64/APKINDEX.tar.gz: ` +
+				`dial tcp: lookup apk.example.com: no such host`,
+			kind:       ApkoErrorRepositoryUnreachable,
+			repository: "https://apk.example.com/os",
+			sentinel:   ErrApkoRepositoryUnreachable,
+			transient:  true,
+		},
+		{
+			name:       "Repository service unavailable",
+			output:     `Error: fetching https://apk.example.com/os: 503 Service Unavailable`,
+			kind:       ApkoErrorRepositoryUnreachable,
+			repository: "https://apk.example.com/os",
+			sentinel:   ErrApkoRepositoryUnreachable,
+			transient:  true,
+		},
+		{
+			name:     "Invalid config",
+			output:   `Error: failed to load image configuration: yaml: unmarshal errors: line 3: field foo not found`,
+			kind:     ApkoErrorInvalidConfig,
+			sentinel: ErrApkoInvalidConfig,
+		},
+		{
+			name:     "Package name containing signature",
+			output:   `Error: failed to install packages: could not find package "py3-signature" in indexes`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "py3-signature",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name:     "Package name containing keyring",
+			output:   `Error: solving "gnupg-keyring" constraint: could not find package "gnupg-keyring" in indexes`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "gnupg-keyring",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name: "Keyring log before unknown package",
+			output: `2024/01/01 12:00:00 INFO appending keyring https://packages.wolfi.dev/os/wolfi-signing.rsa.pub
+Error: failed to install packages: could not find package "does-not-exist" in indexes`,
+			kind:     ApkoErrorUnknownPackage,
+			pkg:      "does-not-exist",
+			sentinel: ErrApkoUnknownPackage,
+		},
+		{
+			name: "Keyring log before unreachable repository",
+			output: `2024/01/01 12:00:00 INFO fetching public key from keyring
+Error: GET https://apk.example.com/os/x86_64/APKINDEX.tar.gz: i/o timeout
+2024/01/01 12:00:01 INFO cleaning up keyring cache`,
+			kind:       ApkoErrorRepositoryUnreachable,
+			repository: "https://apk.example.com/os",
+			sentinel:   ErrApkoRepositoryUnreachable,
+			transient:  true,
+		},
+		{
+			name:     "Unknown failure",
+			output:   "something\nError: disk full\n",
+			kind:     ApkoErrorUnknown,
+			sentinel: ErrApkoUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyApkoError(tt.output)
+			if got == nil {
+				t.Fatal("ClassifyApkoError() returned nil")
+			}
+
+			if got.Kind != tt.kind {
+				t.Errorf("Kind = %s, want %s (message %q)", got.Kind, tt.kind, got.Message)
+			}
+
+			if got.Package != tt.pkg {
+				t.Errorf("Package = %q, want %q", got.Package, tt.pkg)
+			}
+
+			if got.Repository != tt.repository {
+				t.Errorf("Repository = %q, want %q", got.Repository, tt.repository)
+			}
+
+			if !errors.Is(got, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", got, tt.sentinel)
+			}
+
+			if got.Transient() != tt.transient {
+				t.Errorf("Transient() = %v, want %v", got.Transient(), tt.transient)
+			}
+
+			if got.Output != tt.output {
+				t.Error("Output does not hold the complete apko output")
+			}
+		})
+	}
+}
+
+func TestClassifyApkoErrorEmpty(t *testing.T) {
+	got := ClassifyApkoError("  \n ")
+	if got == nil {
+		t.Fatal("ClassifyApkoError() returned nil")
+	}
+
+	if got.Kind != ApkoErrorUnknown {
+		t.Errorf("Kind = %s, want %s", got.Kind, ApkoErrorUnknown)
+	}
+
+	if got.Transient() {
+		t.Error("Transient() = true for empty output")
+	}
+
+	var err error = got
+	if !errors.Is(err, ErrApkoUnknown) || err.Error() != ErrApkoUnknown.Error() {
+		t.Errorf("error = %v, want %v", err, ErrApkoUnknown)
+	}
+}
+
+func TestApkoBuildErrorNil(t *testing.T) {
+	var buildErr *ApkoBuildError
+
+	if buildErr.Transient() {
+		t.Error("Transient() = true for nil error")
+	}
+
+	if got := buildErr.Error(); got != "<nil>" {
+		t.Errorf("Error() = %q, want %q", got, "<nil>")
+	}
+
+	if got := buildErr.Unwrap(); got != nil {
+		t.Errorf("Unwrap() = %v, want nil", got)
+	}
+
+	if IsTransientApkoError(buildErr) {
+		t.Error("IsTransientApkoError() = true for nil error")
+	}
+}
+
+func TestIsTransientApkoError(t *testing.T) {
+	transient := ClassifyApkoError("Error: GET https://apk.example.com/os/x86_64/APKINDEX.tar.gz: i/o timeout")
+	wrapped := fmt.Errorf("build failed: %w", transient)
+
+	if !IsTransientApkoError(wrapped) {
+		t.Error("IsTransientApkoError() = false for wrapped transient error")
+	}
+
+	if IsTransientApkoError(ClassifyApkoError(`could not find package "foo"`)) {
+		t.Error("IsTransientApkoError() = true for unknown package")
+	}
+
+	if IsTransientApkoError(errors.New("other")) {
+		t.Error("IsTransientApkoError() = true for unrelated error")
+	}
+}
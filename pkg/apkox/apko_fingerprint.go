@@ -0,0 +1,238 @@
+package apkox
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// fingerprintPrefix is the algorithm prefix of keyring fingerprints.
+const fingerprintPrefix = "sha256:"
+
+// maxKeyringSize is the maximum size of a public key downloaded by KeyringRegistry.Fetch.
+const maxKeyringSize = 64 << 10
+
+// fingerprintRegex matches a SHA-256 fingerprint, with or without the algorithm prefix.
+var fingerprintRegex = regexp.MustCompile(`^(sha256:)?[a-fA-F0-9]{64}$`)
+
+// ParseRSAPublicKey parses the PEM-encoded RSA public key of a keyring.
+// Both PKIX ("PUBLIC KEY") and PKCS#1 ("RSA PUBLIC KEY") encodings are accepted.
+// It returns an error if the content is not a PEM block or does not hold an RSA public key.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("keyring content is not PEM encoded")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not an RSA key: %T", key)
+		}
+
+		return rsaKey, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+}
+
+// KeyFingerprint computes the fingerprint of the PEM-encoded RSA public key of a keyring.
+// The fingerprint is the SHA-256 digest of the PKIX DER encoding of the key, in the
+// "sha256:<hex>" format, so it does not depend on how the key was encoded.
+//
+// Example:
+//
+//	fp, err := KeyFingerprint(pemBytes)
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(fp) // Output: sha256:3f2a...
+func KeyFingerprint(data []byte) (string, error) {
+	key, err := ParseRSAPublicKey(data)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	sum := sha256.Sum256(der)
+
+	return fingerprintPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// KeyFingerprintFromFile computes the fingerprint of the RSA public key stored in the given file.
+func KeyFingerprintFromFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keyring %s: %w", path, err)
+	}
+
+	return KeyFingerprint(data)
+}
+
+// IsFingerprintFormatValid reports whether the fingerprint is a SHA-256 hex digest,
+// with or without the "sha256:" prefix.
+func IsFingerprintFormatValid(fingerprint string) bool {
+	return fingerprintRegex.MatchString(fingerprint)
+}
+
+// VerifyKeyFingerprint checks that the fingerprint of the PEM-encoded RSA public key matches the expected one.
+// The comparison is case-insensitive and the "sha256:" prefix of 'expected' is optional.
+// It returns an error if the key cannot be parsed or the fingerprints differ.
+func VerifyKeyFingerprint(data []byte, expected string) error {
+	if !IsFingerprintFormatValid(expected) {
+		return fmt.Errorf("invalid expected fingerprint: %s", expected)
+	}
+
+	actual, err := KeyFingerprint(data)
+	if err != nil {
+		return err
+	}
+
+	want := normalizeFingerprint(expected)
+	if actual != want {
+		return fmt.Errorf("keyring fingerprint mismatch: expected %s, got %s", want, actual)
+	}
+
+	return nil
+}
+
+// Verify checks that the PEM-encoded RSA public key matches the fingerprint pinned in the preset.
+// It returns an error if the preset has no pinned fingerprint or the key does not match it.
+func (p KeyringPreset) Verify(data []byte) error {
+	if p.Fingerprint == "" {
+		return fmt.Errorf("keyring preset %s has no pinned fingerprint", p.Name)
+	}
+
+	if err := VerifyKeyFingerprint(data, p.Fingerprint); err != nil {
+		return fmt.Errorf("keyring preset %s: %w", p.Name, err)
+	}
+
+	return nil
+}
+
+// VerifyFile checks that the RSA public key stored in the given file matches the fingerprint pinned in the preset.
+func (p KeyringPreset) VerifyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read keyring %s: %w", path, err)
+	}
+
+	return p.Verify(data)
+}
+
+// VerifyPresetKey looks up the preset registered under the given name and checks that the
+// PEM-encoded RSA public key matches its pinned fingerprint.
+func (r *KeyringRegistry) VerifyPresetKey(name string, data []byte) error {
+	preset, err := r.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	return preset.Verify(data)
+}
+
+// Pin sets the expected fingerprint of a registered preset, such as a built-in preset, so its key
+// can be verified. Pinning a preset again to the same fingerprint is a no-op.
+// It returns an error if the preset is not registered, the fingerprint is not a SHA-256 hex digest,
+// or the preset is already pinned to a different fingerprint.
+func (r *KeyringRegistry) Pin(name, fingerprint string) error {
+	if !IsFingerprintFormatValid(fingerprint) {
+		return fmt.Errorf("invalid fingerprint for preset %s: %s", name, fingerprint)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preset, ok := r.presets[name]
+	if !ok {
+		return fmt.Errorf("unsupported preset: %s", name)
+	}
+
+	pinned := normalizeFingerprint(fingerprint)
+	if preset.Fingerprint != "" && normalizeFingerprint(preset.Fingerprint) != pinned {
+		return fmt.Errorf("keyring preset %s is already pinned to %s", name, preset.Fingerprint)
+	}
+
+	preset.Fingerprint = pinned
+	r.presets[name] = preset
+
+	return nil
+}
+
+// Fetch downloads the public key of the preset registered under the given name from its KeyURL,
+// and verifies it against the pinned fingerprint (see KeyringPreset.Verify).
+// A nil client uses http.DefaultClient.
+// It returns an error if the preset is not registered or not pinned, before any download, or if the
+// download fails or the key does not match.
+func (r *KeyringRegistry) Fetch(ctx context.Context, client *http.Client, name string) ([]byte, error) {
+	preset, err := r.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if preset.Fingerprint == "" {
+		return nil, fmt.Errorf("keyring preset %s has no pinned fingerprint", name)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, preset.KeyURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keyring request for preset %s: %w", name, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download keyring of preset %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download keyring of preset %s: unexpected status %s", name, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxKeyringSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring of preset %s: %w", name, err)
+	}
+
+	if err := preset.Verify(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// normalizeFingerprint returns the fingerprint in lower case, with the "sha256:" prefix.
+func normalizeFingerprint(fingerprint string) string {
+	return fingerprintPrefix + strings.ToLower(strings.TrimPrefix(fingerprint, fingerprintPrefix))
+}
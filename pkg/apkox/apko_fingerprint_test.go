@@ -0,0 +1,273 @@
+package apkox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func generateTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	return key
+}
+
+func encodePKIXPublicKey(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	key := generateTestRSAKey(t)
+	pkix := encodePKIXPublicKey(t, &key.PublicKey)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)})
+
+	fp, err := KeyFingerprint(pkix)
+	if err != nil {
+		t.Fatalf("KeyFingerprint() error = %v", err)
+	}
+
+	if !strings.HasPrefix(fp, "sha256:") || !IsFingerprintFormatValid(fp) {
+		t.Errorf("KeyFingerprint() = %s, want sha256:<hex>", fp)
+	}
+
+	fpPKCS1, err := KeyFingerprint(pkcs1)
+	if err != nil {
+		t.Fatalf("KeyFingerprint() PKCS#1 error = %v", err)
+	}
+
+	if fp != fpPKCS1 {
+		t.Errorf("fingerprint depends on encoding: PKIX %s, PKCS#1 %s", fp, fpPKCS1)
+	}
+
+	other, err := KeyFingerprint(encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other == fp {
+		t.Error("different keys must have different fingerprints")
+	}
+
+	t.Run("Invalid content", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invalid := map[string][]byte{
+			"not PEM":          []byte("not a key"),
+			"unsupported type": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}),
+			"corrupted key":    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1, 2, 3}}),
+			"non-RSA key":      encodePKIXPublicKey(t, &ecKey.PublicKey),
+		}
+
+		for name, data := range invalid {
+			if _, err := KeyFingerprint(data); err == nil {
+				t.Errorf("%s: KeyFingerprint() expected error", name)
+			}
+		}
+	})
+}
+
+func TestVerifyKeyFingerprint(t *testing.T) {
+	key := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+	rotated := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+
+	fp, err := KeyFingerprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyKeyFingerprint(key, fp); err != nil {
+		t.Errorf("VerifyKeyFingerprint() error = %v", err)
+	}
+
+	if err := VerifyKeyFingerprint(key, strings.ToUpper(strings.TrimPrefix(fp, "sha256:"))); err != nil {
+		t.Errorf("VerifyKeyFingerprint() without prefix error = %v", err)
+	}
+
+	if err := VerifyKeyFingerprint(rotated, fp); err == nil {
+		t.Error("VerifyKeyFingerprint() expected error for rotated key")
+	}
+
+	if err := VerifyKeyFingerprint(key, "abc"); err == nil {
+		t.Error("VerifyKeyFingerprint() expected error for malformed fingerprint")
+	}
+}
+
+func TestKeyringPresetVerify(t *testing.T) {
+	key := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+	tampered := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+
+	fp, err := KeyFingerprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "internal.rsa.pub")
+	if err := os.WriteFile(path, key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := KeyFingerprintFromFile(path)
+	if err != nil || fromFile != fp {
+		t.Errorf("KeyFingerprintFromFile() = %s, %v; want %s", fromFile, err, fp)
+	}
+
+	registry := NewKeyringRegistry()
+	if err := registry.Register(KeyringPreset{
+		Name:        "internal",
+		KeyPath:     "/etc/apk/keys/internal.rsa.pub",
+		KeyURL:      "https://apk.example.com/keys/internal.rsa.pub",
+		Fingerprint: fp,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.VerifyPresetKey("internal", key); err != nil {
+		t.Errorf("VerifyPresetKey() error = %v", err)
+	}
+
+	if err := registry.VerifyPresetKey("internal", tampered); err == nil {
+		t.Error("VerifyPresetKey() expected error for tampered key")
+	}
+
+	if err := registry.VerifyPresetKey(KeyringPresetWolfi, key); err == nil {
+		t.Error("VerifyPresetKey() expected error for preset without pinned fingerprint")
+	}
+
+	if err := registry.VerifyPresetKey("missing", key); err == nil {
+		t.Error("VerifyPresetKey() expected error for unknown preset")
+	}
+
+	preset, _ := registry.Lookup("internal")
+	if err := preset.VerifyFile(path); err != nil {
+		t.Errorf("VerifyFile() error = %v", err)
+	}
+
+	if err := preset.VerifyFile(filepath.Join(dir, "missing.pub")); err == nil {
+		t.Error("VerifyFile() expected error for missing file")
+	}
+}
+
+func TestKeyringRegistryPin(t *testing.T) {
+	fp, err := KeyFingerprint(encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewKeyringRegistry()
+
+	if err := registry.Pin(KeyringPresetWolfi, strings.ToUpper(strings.TrimPrefix(fp, "sha256:"))); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	if preset, _ := registry.Lookup(KeyringPresetWolfi); preset.Fingerprint != fp {
+		t.Errorf("Fingerprint = %s, want %s", preset.Fingerprint, fp)
+	}
+
+	if err := registry.Pin(KeyringPresetWolfi, fp); err != nil {
+		t.Errorf("Pin() with the same fingerprint error = %v", err)
+	}
+
+	if err := registry.Pin(KeyringPresetWolfi, "sha256:"+strings.Repeat("0", 64)); err == nil {
+		t.Error("Pin() expected error for a different fingerprint")
+	}
+
+	if err := registry.Pin("missing", fp); err == nil {
+		t.Error("Pin() expected error for unknown preset")
+	}
+
+	if err := registry.Pin(KeyringPresetAlpine, "abc"); err == nil {
+		t.Error("Pin() expected error for malformed fingerprint")
+	}
+}
+
+func TestApkoBuilderFetchKeyrings(t *testing.T) {
+	key := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+	tampered := encodePKIXPublicKey(t, &generateTestRSAKey(t).PublicKey)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/internal.rsa.pub":
+			_, _ = w.Write(key)
+		case "/tampered.rsa.pub":
+			_, _ = w.Write(tampered)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fp, err := KeyFingerprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewKeyringRegistry()
+	for _, name := range []string{"internal", "tampered", "missing"} {
+		if err := registry.Register(KeyringPreset{
+			Name:        name,
+			KeyPath:     "/etc/apk/keys/" + name + ".rsa.pub",
+			KeyURL:      server.URL + "/" + name + ".rsa.pub",
+			Fingerprint: fp,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builder := func(presets ...string) *ApkoBuilder {
+		b := NewApkoBuilder().WithKeyringRegistry(registry)
+		for _, p := range presets {
+			b.WithKeyringPreset(p)
+		}
+
+		return b
+	}
+
+	keyrings, err := builder("internal", "internal").FetchKeyrings(context.Background(), server.Client())
+	if err != nil {
+		t.Fatalf("FetchKeyrings() error = %v", err)
+	}
+
+	if len(keyrings) != 1 || keyrings[0].Preset.Name != "internal" || string(keyrings[0].Data) != string(key) {
+		t.Errorf("FetchKeyrings() = %+v, want the internal key once", keyrings)
+	}
+
+	errCases := map[string]*ApkoBuilder{
+		"fingerprint mismatch":  builder("internal", "tampered"),
+		"unexpected status":     builder("missing"),
+		"no pinned fingerprint": builder().WithWolfiKeyring(),
+		"unsupported preset":    builder("unknown"),
+	}
+
+	for errText, b := range errCases {
+		if _, err := b.FetchKeyrings(context.Background(), server.Client()); err == nil ||
+			!strings.Contains(err.Error(), errText) {
+			t.Errorf("FetchKeyrings() error = %v, want %q", err, errText)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package apkox
+
+import (
+	"fmt"
+
+	"github.com/Excoriate/daggerx/pkg/containerx"
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+// WithMirrorTable sets the mirror table the image references consumed by the builder are rewritten
+// through, e.g. to pull the apko image from a pull-through cache. Digests are kept intact.
+// It returns the updated ApkoBuilder instance.
+func (b *ApkoBuilder) WithMirrorTable(table *containerx.MirrorTable) *ApkoBuilder {
+	b.mirrorTable = table
+	return b
+}
+
+// ApkoImage returns the reference of the apko image (ApkoDefaultRepositoryURL) to run the build with,
+// rewritten through the mirror table set by WithMirrorTable.
+// The version is a tag or a digest (e.g., "sha256:..."); if empty, "latest" is used.
+//
+// Example:
+//
+//	table, err := containerx.LoadMirrorTable("mirrors.yaml")
+//	if err != nil {
+//	    // handle error
+//	}
+//	image, err := NewApkoBuilder().WithMirrorTable(table).ApkoImage("latest")
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr := client.Container().From(image)
+func (b *ApkoBuilder) ApkoImage(version string) (string, error) {
+	if version == "" {
+		version = fixtures.ImageVersion
+	}
+
+	image := fmt.Sprintf("%s:%s", ApkoDefaultRepositoryURL, version)
+	if _, err := containerx.Parse(ApkoDefaultRepositoryURL + "@" + version); err == nil {
+		image = ApkoDefaultRepositoryURL + "@" + version
+	}
+
+	rewritten, err := b.mirrorTable.RewriteString(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the apko image: %w", err)
+	}
+
+	return rewritten, nil
+}
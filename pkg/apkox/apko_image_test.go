@@ -0,0 +1,43 @@
+package apkox
+
+import (
+	"testing"
+
+	"github.com/Excoriate/daggerx/pkg/containerx"
+)
+
+func TestApkoBuilderApkoImage(t *testing.T) {
+	digest := "sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef"
+	table := &containerx.MirrorTable{Mirrors: []containerx.MirrorRule{
+		{Source: "cgr.dev/chainguard", Mirror: "cache.internal/chainguard"},
+	}}
+
+	tests := []struct {
+		name    string
+		builder *ApkoBuilder
+		version string
+		want    string
+	}{
+		{"Default version", NewApkoBuilder(), "", "cgr.dev/chainguard/apko:latest"},
+		{"Tag", NewApkoBuilder(), "v0.19.0", "cgr.dev/chainguard/apko:v0.19.0"},
+		{"Mirrored tag", NewApkoBuilder().WithMirrorTable(table), "v0.19.0", "cache.internal/chainguard/apko:v0.19.0"},
+		{"Mirrored digest", NewApkoBuilder().WithMirrorTable(table), digest, "cache.internal/chainguard/apko@" + digest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.ApkoImage(tt.version)
+			if err != nil {
+				t.Fatalf("ApkoImage() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ApkoImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewApkoBuilder().ApkoImage("bad tag!"); err == nil {
+		t.Error("ApkoImage() expected error for an invalid version")
+	}
+}
@@ -0,0 +1,240 @@
+package apkox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeyringPresetAlpine is the name of the built-in Alpine keyring preset.
+	KeyringPresetAlpine = "alpine"
+	// KeyringPresetWolfi is the name of the built-in Wolfi keyring preset.
+	KeyringPresetWolfi = "wolfi"
+)
+
+// KeyringPreset is a named keyring that can be added to a build by name.
+type KeyringPreset struct {
+	// Name is the unique name of the preset (e.g., "wolfi").
+	Name string
+	// KeyPath is the path of the public key inside the build container. It must be under "/etc/apk/keys/".
+	KeyPath string
+	// KeyURL is the HTTPS URL the public key can be downloaded from.
+	KeyURL string
+	// Fingerprint is the expected SHA-256 fingerprint of the public key (see KeyFingerprint).
+	// It may be empty if the key is not pinned; unpinned keys cannot be fetched (see KeyringRegistry.Pin).
+	Fingerprint string
+}
+
+// KeyringInfo returns the path and URL of the preset as a KeyringInfo.
+func (p KeyringPreset) KeyringInfo() KeyringInfo {
+	return KeyringInfo{KeyURL: p.KeyURL, KeyPath: p.KeyPath}
+}
+
+// KeyringRegistry holds the keyring presets that can be referenced by name.
+// It is safe for concurrent use. Built-in presets ("alpine" and "wolfi") are always registered
+// and cannot be replaced.
+type KeyringRegistry struct {
+	mu      sync.RWMutex
+	presets map[string]KeyringPreset
+}
+
+// DefaultKeyringRegistry is the registry used by GetKeyringInfoForPreset and by builders
+// that do not set their own registry through WithKeyringRegistry.
+var DefaultKeyringRegistry = NewKeyringRegistry()
+
+// NewKeyringRegistry creates a keyring registry holding the built-in presets.
+// The built-in presets must be pinned (see KeyringRegistry.Pin) before their keys can be fetched and verified.
+func NewKeyringRegistry() *KeyringRegistry {
+	return &KeyringRegistry{
+		presets: map[string]KeyringPreset{
+			KeyringPresetAlpine: {
+				Name:    KeyringPresetAlpine,
+				KeyURL:  "https://alpinelinux.org/keys/alpine-devel@lists.alpinelinux.org-4a6a0840.rsa.pub",
+				KeyPath: ApkoAlpineSigninRsaKeyPath,
+			},
+			KeyringPresetWolfi: {
+				Name:    KeyringPresetWolfi,
+				KeyURL:  "https://packages.wolfi.dev/os/wolfi-signing.rsa.pub",
+				KeyPath: ApkoWolfiSigninRsaKeyPath,
+			},
+		},
+	}
+}
+
+// Register adds a preset to the registry.
+// It returns an error if the name is empty or already registered, if the key path is not under
+// "/etc/apk/keys/", if the key URL is not a valid HTTPS URL, or if the fingerprint is set but is not
+// a SHA-256 hex digest.
+//
+// Example:
+//
+//	err := DefaultKeyringRegistry.Register(KeyringPreset{
+//	    Name:    "internal",
+//	    KeyPath: "/etc/apk/keys/internal.rsa.pub",
+//	    KeyURL:  "https://apk.example.com/keys/internal.rsa.pub",
+//	})
+func (r *KeyringRegistry) Register(preset KeyringPreset) error {
+	if strings.TrimSpace(preset.Name) == "" {
+		return fmt.Errorf("keyring preset name is required")
+	}
+
+	if !strings.HasPrefix(preset.KeyPath, "/etc/apk/keys/") {
+		return fmt.Errorf("invalid keyring path for preset %s: %s", preset.Name, preset.KeyPath)
+	}
+
+	if err := validateURL(preset.KeyURL, true); err != nil {
+		return fmt.Errorf("invalid keyring URL for preset %s: %w", preset.Name, err)
+	}
+
+	if preset.Fingerprint != "" && !IsFingerprintFormatValid(preset.Fingerprint) {
+		return fmt.Errorf("invalid fingerprint for preset %s: %s", preset.Name, preset.Fingerprint)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.presets[preset.Name]; exists {
+		return fmt.Errorf("keyring preset already registered: %s", preset.Name)
+	}
+
+	r.presets[preset.Name] = preset
+
+	return nil
+}
+
+// Lookup returns the preset registered under the given name.
+// It returns an error if no preset is registered with that name.
+func (r *KeyringRegistry) Lookup(name string) (KeyringPreset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preset, ok := r.presets[name]
+	if !ok {
+		return KeyringPreset{}, fmt.Errorf("unsupported preset: %s", name)
+	}
+
+	return preset, nil
+}
+
+// List returns every registered preset, sorted by name.
+func (r *KeyringRegistry) List() []KeyringPreset {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	presets := make([]KeyringPreset, 0, len(r.presets))
+	for _, p := range r.presets {
+		presets = append(presets, p)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets
+}
+
+// RegisterKeyringPreset adds a preset to the DefaultKeyringRegistry.
+func RegisterKeyringPreset(preset KeyringPreset) error {
+	return DefaultKeyringRegistry.Register(preset)
+}
+
+// WithKeyringRegistry sets the registry used to resolve keyring presets.
+// If not set, the DefaultKeyringRegistry is used.
+// It returns the updated ApkoBuilder instance.
+func (b *ApkoBuilder) WithKeyringRegistry(registry *KeyringRegistry) *ApkoBuilder {
+	b.keyringRegistry = registry
+	return b
+}
+
+// WithKeyringPreset adds the keyring registered under the given preset name to the APKO build.
+// The preset is resolved when the command is generated, so an unknown preset is reported
+// as an error by BuildCommand instead of being skipped.
+// It returns the updated ApkoBuilder instance.
+func (b *ApkoBuilder) WithKeyringPreset(name string) *ApkoBuilder {
+	b.keyringPresets = append(b.keyringPresets, name)
+	return b
+}
+
+// FetchedKeyring is the public key of a keyring preset, downloaded and verified against its pinned fingerprint.
+type FetchedKeyring struct {
+	// Preset is the keyring preset.
+	Preset KeyringPreset
+	// Data is the PEM-encoded public key, to be written to Preset.KeyPath in the build container.
+	Data []byte
+}
+
+// FetchKeyrings downloads the public keys of the presets used by the build (WithKeyringPreset,
+// WithWolfiKeyring and WithAlpineKeyring) and verifies them against their pinned fingerprints
+// (see KeyringRegistry.Fetch), so a tampered or rotated key is caught before the build.
+// A nil client uses http.DefaultClient.
+// It returns the keys in the order the presets were added, or an error for the first preset that
+// is unknown, unpinned, cannot be downloaded or does not match its fingerprint.
+//
+// Example:
+//
+//	keyrings, err := builder.FetchKeyrings(ctx, nil)
+//	if err != nil {
+//	    // handle error
+//	}
+//	for _, k := range keyrings {
+//	    ctr = ctr.WithNewFile(k.Preset.KeyPath, string(k.Data))
+//	}
+func (b *ApkoBuilder) FetchKeyrings(ctx context.Context, client *http.Client) ([]FetchedKeyring, error) {
+	names := appendUnique(nil, b.keyringPresets...)
+
+	if b.wolfiKeyring {
+		names = appendUnique(names, KeyringPresetWolfi)
+	}
+
+	if b.alpineKeyring {
+		names = appendUnique(names, KeyringPresetAlpine)
+	}
+
+	keyrings := make([]FetchedKeyring, 0, len(names))
+
+	for _, name := range names {
+		data, err := b.registry().Fetch(ctx, client, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch keyring preset: %w", err)
+		}
+
+		preset, err := b.registry().Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		keyrings = append(keyrings, FetchedKeyring{Preset: preset, Data: data})
+	}
+
+	return keyrings, nil
+}
+
+// registry returns the keyring registry of the builder.
+func (b *ApkoBuilder) registry() *KeyringRegistry {
+	if b.keyringRegistry != nil {
+		return b.keyringRegistry
+	}
+
+	return DefaultKeyringRegistry
+}
+
+// resolveKeyringPresets returns the key paths of the configured keyring presets.
+// It returns an error if any preset is not registered.
+func (b *ApkoBuilder) resolveKeyringPresets() ([]string, error) {
+	paths := make([]string, 0, len(b.keyringPresets))
+
+	for _, name := range b.keyringPresets {
+		preset, err := b.registry().Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve keyring preset: %w", err)
+		}
+
+		paths = append(paths, preset.KeyPath)
+	}
+
+	return paths, nil
+}
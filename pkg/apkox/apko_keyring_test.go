@@ -0,0 +1,120 @@
+package apkox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyringRegistry(t *testing.T) {
+	internal := KeyringPreset{
+		Name:        "internal",
+		KeyPath:     "/etc/apk/keys/internal.rsa.pub",
+		KeyURL:      "https://apk.example.com/keys/internal.rsa.pub",
+		Fingerprint: "sha256:" + strings.Repeat("a", 64),
+	}
+
+	t.Run("Built-in presets", func(t *testing.T) {
+		registry := NewKeyringRegistry()
+
+		names := make([]string, 0)
+		for _, p := range registry.List() {
+			names = append(names, p.Name)
+		}
+
+		if !reflect.DeepEqual(names, []string{KeyringPresetAlpine, KeyringPresetWolfi}) {
+			t.Errorf("List() names = %v", names)
+		}
+	})
+
+	t.Run("Register and lookup", func(t *testing.T) {
+		registry := NewKeyringRegistry()
+		if err := registry.Register(internal); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		got, err := registry.Lookup("internal")
+		if err != nil {
+			t.Fatalf("Lookup() error = %v", err)
+		}
+
+		if got != internal {
+			t.Errorf("Lookup() = %+v, want %+v", got, internal)
+		}
+
+		if len(registry.List()) != 3 {
+			t.Errorf("List() length = %d, want 3", len(registry.List()))
+		}
+	})
+
+	t.Run("Invalid presets", func(t *testing.T) {
+		registry := NewKeyringRegistry()
+
+		invalid := []KeyringPreset{
+			{KeyPath: internal.KeyPath, KeyURL: internal.KeyURL},
+			{Name: "bad-path", KeyPath: "/tmp/key.pub", KeyURL: internal.KeyURL},
+			{Name: "bad-url", KeyPath: internal.KeyPath, KeyURL: "http://apk.example.com/key.pub"},
+			{Name: "bad-fingerprint", KeyPath: internal.KeyPath, KeyURL: internal.KeyURL, Fingerprint: "md5:abc"},
+			{Name: KeyringPresetWolfi, KeyPath: internal.KeyPath, KeyURL: internal.KeyURL},
+		}
+
+		for _, p := range invalid {
+			if err := registry.Register(p); err == nil {
+				t.Errorf("Register(%+v) expected error", p)
+			}
+		}
+
+		if _, err := registry.Lookup("unknown"); err == nil {
+			t.Error("Lookup() expected error for unknown preset")
+		}
+	})
+}
+
+func TestApkoBuilderKeyringPresets(t *testing.T) {
+	registry := NewKeyringRegistry()
+	if err := registry.Register(KeyringPreset{
+		Name:    "internal",
+		KeyPath: "/etc/apk/keys/internal.rsa.pub",
+		KeyURL:  "https://apk.example.com/keys/internal.rsa.pub",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	base := func() *ApkoBuilder {
+		return NewApkoBuilder().
+			WithConfigFile("config.yaml").
+			WithOutputImage("my-image").
+			WithOutputTarball("output.tar").
+			WithSBOM(true).
+			WithVCS(true).
+			WithKeyringRegistry(registry)
+	}
+
+	t.Run("Custom preset", func(t *testing.T) {
+		cmd, err := base().WithKeyringPreset("internal").WithKeyRingWolfi().BuildCommand()
+		if err != nil {
+			t.Fatalf("BuildCommand returned unexpected error: %v", err)
+		}
+
+		expected := []string{
+			"apko", "build",
+			"--keyring-append", ApkoWolfiSigninRsaKeyPath,
+			"--keyring-append", "/etc/apk/keys/internal.rsa.pub",
+			"config.yaml", "my-image:latest", "output.tar",
+		}
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("Unknown preset", func(t *testing.T) {
+		if _, err := base().WithKeyringPreset("missing").BuildCommand(); err == nil {
+			t.Error("BuildCommand() expected error for unknown preset")
+		}
+
+		if _, err := NewApkoPublishBuilder(base().WithKeyringPreset("missing")).ShowConfigCommand(); err == nil {
+			t.Error("ShowConfigCommand() expected error for unknown preset")
+		}
+	})
+}
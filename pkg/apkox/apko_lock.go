@@ -0,0 +1,459 @@
+package apkox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ApkoLock is the Go representation of the "*.lock.json" file produced by "apko lock".
+type ApkoLock struct {
+	// Version is the lockfile schema version (e.g., "v1").
+	Version string `json:"version"`
+	// Config identifies the configuration the lockfile was resolved from.
+	Config *ApkoLockConfig `json:"config,omitempty"`
+	// Contents holds the resolved keyrings, repositories and packages.
+	Contents ApkoLockContents `json:"contents"`
+}
+
+// ApkoLockConfig identifies the configuration file a lockfile was generated from.
+type ApkoLockConfig struct {
+	// Name is the name of the configuration file.
+	Name string `json:"name"`
+	// Checksum is the checksum of the configuration file.
+	Checksum string `json:"checksum"`
+}
+
+// ApkoLockContents holds the resolved contents of a lockfile.
+type ApkoLockContents struct {
+	// Keyrings lists the keys used to verify the packages.
+	Keyrings []ApkoLockKeyring `json:"keyring"`
+	// BuildRepositories lists the repositories used only at build time.
+	BuildRepositories []ApkoLockRepository `json:"build_repositories,omitempty"`
+	// Repositories lists the resolved repositories, per architecture.
+	Repositories []ApkoLockRepository `json:"repositories"`
+	// Packages lists the resolved packages, per architecture.
+	Packages []ApkoLockPackage `json:"packages"`
+}
+
+// ApkoLockKeyring is a keyring entry of a lockfile.
+type ApkoLockKeyring struct {
+	// Name is the name of the key.
+	Name string `json:"name"`
+	// URL is the location the key was fetched from.
+	URL string `json:"url"`
+}
+
+// ApkoLockRepository is a repository entry of a lockfile.
+type ApkoLockRepository struct {
+	// Name is the name of the repository.
+	Name string `json:"name"`
+	// URL is the index URL of the repository.
+	URL string `json:"url"`
+	// Architecture is the architecture the repository index was resolved for.
+	Architecture string `json:"architecture"`
+}
+
+// ApkoLockPackage is a resolved package of a lockfile.
+type ApkoLockPackage struct {
+	// Name is the package name.
+	Name string `json:"name"`
+	// URL is the location the package is downloaded from.
+	URL string `json:"url"`
+	// Version is the resolved package version.
+	Version string `json:"version"`
+	// Architecture is the architecture of the package.
+	Architecture string `json:"architecture"`
+	// Signature is the byte range and checksum of the package signature section.
+	Signature ApkoLockRange `json:"signature"`
+	// Control is the byte range and checksum of the package control section.
+	Control ApkoLockRange `json:"control"`
+	// Data is the byte range and checksum of the package data section.
+	Data ApkoLockRange `json:"data"`
+	// Checksum is the APK checksum of the package.
+	Checksum string `json:"checksum"`
+}
+
+// ApkoLockRange is a byte range of an APK package together with its checksum.
+type ApkoLockRange struct {
+	// Range is the HTTP byte range of the section (e.g., "bytes=0-700").
+	Range string `json:"range"`
+	// Checksum is the checksum of the section.
+	Checksum string `json:"checksum"`
+}
+
+// ApkoLockChange describes a package whose resolution differs between two lockfiles.
+type ApkoLockChange struct {
+	// Name is the package name.
+	Name string
+	// Architecture is the architecture of the package.
+	Architecture string
+	// OldVersion is the version in the previous lockfile. Empty for added packages.
+	OldVersion string
+	// NewVersion is the version in the current lockfile. Empty for removed packages.
+	NewVersion string
+}
+
+// ApkoLockDiff reports the differences between two lockfiles.
+type ApkoLockDiff struct {
+	// Added lists the packages only present in the current lockfile.
+	Added []ApkoLockChange
+	// Removed lists the packages only present in the previous lockfile.
+	Removed []ApkoLockChange
+	// Upgraded lists the packages whose version increased.
+	Upgraded []ApkoLockChange
+	// Downgraded lists the packages whose version decreased.
+	Downgraded []ApkoLockChange
+	// Rebuilt lists the packages whose version is the same but whose checksum changed.
+	Rebuilt []ApkoLockChange
+}
+
+// ParseApkoLock parses the content of an apko lockfile.
+// It returns an error if the content is empty, is not valid JSON, or has packages without a name.
+func ParseApkoLock(data []byte) (*ApkoLock, error) {
+	if len(data) == 0 {
+		return nil, errors.New("apko lockfile content is empty")
+	}
+
+	var lock ApkoLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse apko lockfile: %w", err)
+	}
+
+	for i, pkg := range lock.Contents.Packages {
+		if pkg.Name == "" {
+			return nil, fmt.Errorf("package at index %d has no name", i)
+		}
+	}
+
+	return &lock, nil
+}
+
+// LoadApkoLock reads and parses the apko lockfile at the given path.
+// The path must have a ".json" extension.
+func LoadApkoLock(path string) (*ApkoLock, error) {
+	if filepath.Ext(path) != ".json" {
+		return nil, fmt.Errorf("lockfile must have a .json extension: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apko lockfile %s: %w", path, err)
+	}
+
+	return ParseApkoLock(data)
+}
+
+// GetLockfilePath returns the lockfile path apko uses by default for a configuration file,
+// replacing the .yaml or .yml extension with ".lock.json".
+//
+// Example:
+//
+//	fmt.Println(GetLockfilePath("/mnt/apko.yaml")) // Output: /mnt/apko.lock.json
+func GetLockfilePath(cfgFile string) string {
+	return strings.TrimSuffix(cfgFile, filepath.Ext(cfgFile)) + ".lock.json"
+}
+
+// PackagesByArch groups the packages of the lockfile by architecture.
+func (l *ApkoLock) PackagesByArch() map[string][]ApkoLockPackage {
+	byArch := make(map[string][]ApkoLockPackage)
+	for _, pkg := range l.Contents.Packages {
+		byArch[pkg.Architecture] = append(byArch[pkg.Architecture], pkg)
+	}
+
+	return byArch
+}
+
+// Package returns the package with the given name and architecture, and whether it was found.
+func (l *ApkoLock) Package(name, arch string) (ApkoLockPackage, bool) {
+	for _, pkg := range l.Contents.Packages {
+		if pkg.Name == name && pkg.Architecture == arch {
+			return pkg, true
+		}
+	}
+
+	return ApkoLockPackage{}, false
+}
+
+// DiffApkoLocks compares a previous and a current lockfile and reports the added, removed,
+// upgraded, downgraded and rebuilt packages. Packages are matched by name and architecture, versions
+// are compared following the apk version ordering, and every list of the result is sorted by
+// architecture and name.
+//
+// Example:
+//
+//	diff := DiffApkoLocks(previous, current)
+//	if diff.HasChanges() {
+//	    return fmt.Errorf("image contents drifted:\n%s", diff)
+//	}
+func DiffApkoLocks(previous, current *ApkoLock) ApkoLockDiff {
+	oldPkgs := indexLockPackages(previous)
+	newPkgs := indexLockPackages(current)
+
+	var diff ApkoLockDiff
+
+	for key, newPkg := range newPkgs {
+		oldPkg, ok := oldPkgs[key]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, ApkoLockChange{
+				Name: newPkg.Name, Architecture: newPkg.Architecture, NewVersion: newPkg.Version,
+			})
+		case oldPkg.Version != newPkg.Version:
+			change := ApkoLockChange{
+				Name: newPkg.Name, Architecture: newPkg.Architecture,
+				OldVersion: oldPkg.Version, NewVersion: newPkg.Version,
+			}
+
+			if compareApkVersions(oldPkg.Version, newPkg.Version) > 0 {
+				diff.Downgraded = append(diff.Downgraded, change)
+			} else {
+				diff.Upgraded = append(diff.Upgraded, change)
+			}
+		case oldPkg.Checksum != newPkg.Checksum:
+			diff.Rebuilt = append(diff.Rebuilt, ApkoLockChange{
+				Name: newPkg.Name, Architecture: newPkg.Architecture,
+				OldVersion: oldPkg.Version, NewVersion: newPkg.Version,
+			})
+		}
+	}
+
+	for key, oldPkg := range oldPkgs {
+		if _, ok := newPkgs[key]; !ok {
+			diff.Removed = append(diff.Removed, ApkoLockChange{
+				Name: oldPkg.Name, Architecture: oldPkg.Architecture, OldVersion: oldPkg.Version,
+			})
+		}
+	}
+
+	for _, changes := range [][]ApkoLockChange{diff.Added, diff.Removed, diff.Upgraded, diff.Downgraded, diff.Rebuilt} {
+		sortLockChanges(changes)
+	}
+
+	return diff
+}
+
+// HasChanges reports whether the diff contains any added, removed, upgraded, downgraded or rebuilt package.
+func (d ApkoLockDiff) HasChanges() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Upgraded)+len(d.Downgraded)+len(d.Rebuilt) > 0
+}
+
+// String returns a human-readable summary of the diff, one change per line: added (+), removed (-),
+// upgraded (~), downgraded (<) and rebuilt (!) packages.
+func (d ApkoLockDiff) String() string {
+	var sb strings.Builder
+
+	for _, c := range d.Added {
+		fmt.Fprintf(&sb, "+ %s (%s) %s\n", c.Name, c.Architecture, c.NewVersion)
+	}
+
+	for _, c := range d.Removed {
+		fmt.Fprintf(&sb, "- %s (%s) %s\n", c.Name, c.Architecture, c.OldVersion)
+	}
+
+	for _, c := range d.Upgraded {
+		fmt.Fprintf(&sb, "~ %s (%s) %s -> %s\n", c.Name, c.Architecture, c.OldVersion, c.NewVersion)
+	}
+
+	for _, c := range d.Downgraded {
+		fmt.Fprintf(&sb, "< %s (%s) %s -> %s\n", c.Name, c.Architecture, c.OldVersion, c.NewVersion)
+	}
+
+	for _, c := range d.Rebuilt {
+		fmt.Fprintf(&sb, "! %s (%s) %s checksum changed\n", c.Name, c.Architecture, c.NewVersion)
+	}
+
+	return sb.String()
+}
+
+// LockCommand generates the "apko lock" command for the builder configuration.
+// The lockfile is written to the path set through WithLockfile; if none is set, apko's default
+// location next to the configuration file is used.
+// It returns a slice of strings representing the command and an error if the configuration file is invalid.
+func (b *ApkoBuilder) LockCommand() ([]string, error) {
+	return NewApkoPublishBuilder(b).LockCommand(b.lockfile)
+}
+
+// indexLockPackages indexes the packages of a lockfile by architecture and name.
+func indexLockPackages(lock *ApkoLock) map[string]ApkoLockPackage {
+	pkgs := make(map[string]ApkoLockPackage)
+	if lock == nil {
+		return pkgs
+	}
+
+	for _, pkg := range lock.Contents.Packages {
+		pkgs[pkg.Architecture+"/"+pkg.Name] = pkg
+	}
+
+	return pkgs
+}
+
+// sortLockChanges sorts changes by architecture and name.
+func sortLockChanges(changes []ApkoLockChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Architecture != changes[j].Architecture {
+			return changes[i].Architecture < changes[j].Architecture
+		}
+
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// apkVersionToken is a component of an apk version, as split by apkVersionTokens.
+type apkVersionToken struct {
+	// rank orders tokens of different kinds at the same position, following apk: a number is greater
+	// than a letter, which is greater than a post-release suffix (e.g., "_p1"), which is greater than the
+	// end of the version, which is greater than a pre-release suffix (e.g., "_rc1").
+	rank  int
+	value string
+}
+
+const (
+	apkTokenPreSuffix = iota - 1
+	apkTokenEnd
+	apkTokenPostSuffix
+	apkTokenLetter
+	apkTokenNumber
+)
+
+// apkSuffixOrder orders the apk version suffixes; the ones before the empty suffix are pre-releases.
+var apkSuffixOrder = []string{"alpha", "beta", "pre", "rc", "", "cvs", "svn", "git", "hg", "p"}
+
+// compareApkVersions compares two apk versions (e.g., "1.2.3_rc1-r0"), returning -1, 0 or 1 when
+// a is older than, equal to or newer than b. The version is compared first, then the "-r" release;
+// a "~" commit suffix is ignored.
+func compareApkVersions(a, b string) int {
+	versionA, releaseA := splitApkRelease(a)
+	versionB, releaseB := splitApkRelease(b)
+
+	tokensA, tokensB := apkVersionTokens(versionA), apkVersionTokens(versionB)
+
+	for i := 0; i < len(tokensA) || i < len(tokensB); i++ {
+		ta, tb := apkVersionToken{rank: apkTokenEnd}, apkVersionToken{rank: apkTokenEnd}
+		if i < len(tokensA) {
+			ta = tokensA[i]
+		}
+
+		if i < len(tokensB) {
+			tb = tokensB[i]
+		}
+
+		if c := compareApkTokens(ta, tb); c != 0 {
+			return c
+		}
+	}
+
+	return compareNumeric(releaseA, releaseB)
+}
+
+// splitApkRelease splits a version into its version and "-r" release number, without the "~" commit suffix.
+func splitApkRelease(v string) (string, string) {
+	v, _, _ = strings.Cut(v, "~")
+
+	if i := strings.LastIndex(v, "-r"); i >= 0 && isDigits(v[i+2:]) {
+		return v[:i], v[i+2:]
+	}
+
+	return v, "0"
+}
+
+// apkVersionTokens splits a version into numbers, letters and "_" suffixes; a suffix number is a number token.
+func apkVersionTokens(v string) []apkVersionToken {
+	var tokens []apkVersionToken
+
+	for i := 0; i < len(v); {
+		c := v[i]
+
+		switch {
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+				end++
+			}
+
+			tokens = append(tokens, apkVersionToken{rank: apkTokenNumber, value: v[i:end]})
+			i = end
+		case c == '_':
+			end := i + 1
+			for end < len(v) && isLetter(v[end]) {
+				end++
+			}
+
+			tokens = append(tokens, apkSuffixToken(v[i+1:end]))
+			i = end
+		case isLetter(c):
+			tokens = append(tokens, apkVersionToken{rank: apkTokenLetter, value: string(c)})
+			i++
+		default:
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// apkSuffixToken returns the token of a suffix, its value being its position in apkSuffixOrder.
+// Unknown suffixes are ordered after the known ones.
+func apkSuffixToken(suffix string) apkVersionToken {
+	i := slices.Index(apkSuffixOrder, suffix)
+	if i < 0 {
+		i = len(apkSuffixOrder)
+	}
+
+	if i < slices.Index(apkSuffixOrder, "") {
+		return apkVersionToken{rank: apkTokenPreSuffix, value: strconv.Itoa(i)}
+	}
+
+	return apkVersionToken{rank: apkTokenPostSuffix, value: strconv.Itoa(i)}
+}
+
+// compareApkTokens compares two version tokens.
+func compareApkTokens(a, b apkVersionToken) int {
+	switch {
+	case a.rank != b.rank:
+		return compareInts(a.rank, b.rank)
+	case a.rank == apkTokenLetter:
+		return strings.Compare(a.value, b.value)
+	default:
+		return compareNumeric(a.value, b.value)
+	}
+}
+
+// compareNumeric compares two strings of digits as numbers of any size.
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInts(len(a), len(b))
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareInts returns -1, 0 or 1 when a is less than, equal to or greater than b.
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isDigits reports whether s is a non-empty string of digits.
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,203 @@
+package apkox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testApkoLockJSON = `{
+  "version": "v1",
+  "config": {"name": "apko.yaml", "checksum": "sha256-abc"},
+  "contents": {
+    "keyring": [
+      {"name": "packages.wolfi.dev/os/wolfi-signing.rsa.pub", "url": "https://packages.wolfi.dev/os/wolfi-signing.rsa.pub"}
+    ],
+    "repositories": [
+      {"name": "packages.wolfi.dev/os", "url": "https://packages.wolfi.dev/os/x86_64/APKINDEX.tar.gz", "architecture": "x86_64"}
+    ],
+    "packages": [
+      {
+        "name": "ca-certificates-bundle",
+        "url": "https://packages.wolfi.dev/os/x86_64/ca-certificates-bundle-20240315-r0.apk",
+        "version": "20240315-r0",
+        "architecture": "x86_64",
+        "signature": {"range": "bytes=0-700", "checksum": "sha1-aaa"},
+        "control": {"range": "bytes=701-1000", "checksum": "sha1-bbb"},
+        "data": {"range": "bytes=1001-5000", "checksum": "sha256-ccc"},
+        "checksum": "Q1aaa"
+      },
+      {"name": "wolfi-base", "version": "1-r5", "architecture": "x86_64", "checksum": "Q1bbb"},
+      {"name": "wolfi-base", "version": "1-r5", "architecture": "aarch64", "checksum": "Q1ccc"}
+    ]
+  }
+}`
+
+func TestParseApkoLock(t *testing.T) {
+	lock, err := ParseApkoLock([]byte(testApkoLockJSON))
+	if err != nil {
+		t.Fatalf("ParseApkoLock() error = %v", err)
+	}
+
+	if lock.Version != "v1" || lock.Config.Name != "apko.yaml" {
+		t.Errorf("unexpected header: %+v", lock)
+	}
+
+	if len(lock.Contents.Keyrings) != 1 || len(lock.Contents.Repositories) != 1 {
+		t.Errorf("unexpected contents: %+v", lock.Contents)
+	}
+
+	pkg, ok := lock.Package("ca-certificates-bundle", "x86_64")
+	if !ok {
+		t.Fatal("Package() did not find ca-certificates-bundle")
+	}
+
+	if pkg.Version != "20240315-r0" || pkg.Data.Checksum != "sha256-ccc" {
+		t.Errorf("unexpected package: %+v", pkg)
+	}
+
+	byArch := lock.PackagesByArch()
+	if len(byArch["x86_64"]) != 2 || len(byArch["aarch64"]) != 1 {
+		t.Errorf("PackagesByArch() = %v", byArch)
+	}
+
+	if _, err := ParseApkoLock(nil); err == nil {
+		t.Error("ParseApkoLock() expected error for empty content")
+	}
+
+	if _, err := ParseApkoLock([]byte(`{"contents": {"packages": [{"version": "1"}]}}`)); err == nil {
+		t.Error("ParseApkoLock() expected error for unnamed package")
+	}
+}
+
+func TestLoadApkoLock(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "apko.lock.json")
+	if err := os.WriteFile(path, []byte(testApkoLockJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadApkoLock(path); err != nil {
+		t.Errorf("LoadApkoLock() error = %v", err)
+	}
+
+	if _, err := LoadApkoLock(filepath.Join(dir, "missing.lock.json")); err == nil {
+		t.Error("LoadApkoLock() expected error for missing file")
+	}
+
+	if _, err := LoadApkoLock(filepath.Join(dir, "apko.lock")); err == nil {
+		t.Error("LoadApkoLock() expected error for invalid extension")
+	}
+}
+
+func TestGetLockfilePath(t *testing.T) {
+	if got := GetLockfilePath("/mnt/apko.yaml"); got != "/mnt/apko.lock.json" {
+		t.Errorf("GetLockfilePath() = %s", got)
+	}
+
+	if got := GetLockfilePath("apko.yml"); got != "apko.lock.json" {
+		t.Errorf("GetLockfilePath() = %s", got)
+	}
+}
+
+func TestDiffApkoLocks(t *testing.T) {
+	previous := &ApkoLock{Contents: ApkoLockContents{Packages: []ApkoLockPackage{
+		{Name: "busybox", Version: "1.36-r1", Architecture: "x86_64", Checksum: "Q1a"},
+		{Name: "curl", Version: "8.5-r0", Architecture: "x86_64", Checksum: "Q1b"},
+		{Name: "glibc", Version: "2.39-r1", Architecture: "x86_64", Checksum: "Q1c"},
+		{Name: "zlib", Version: "1.3-r0", Architecture: "x86_64", Checksum: "Q1d"},
+		{Name: "git", Version: "2.44.0-r1", Architecture: "x86_64", Checksum: "Q1h"},
+	}}}
+
+	current := &ApkoLock{Contents: ApkoLockContents{Packages: []ApkoLockPackage{
+		{Name: "busybox", Version: "1.36-r1", Architecture: "x86_64", Checksum: "Q1a"},
+		{Name: "curl", Version: "8.6-r0", Architecture: "x86_64", Checksum: "Q1e"},
+		{Name: "glibc", Version: "2.39-r1", Architecture: "x86_64", Checksum: "Q1f"},
+		{Name: "openssl", Version: "3.2-r0", Architecture: "x86_64", Checksum: "Q1g"},
+		{Name: "git", Version: "2.9.0-r0", Architecture: "x86_64", Checksum: "Q1i"},
+	}}}
+
+	diff := DiffApkoLocks(previous, current)
+
+	want := ApkoLockDiff{
+		Added:    []ApkoLockChange{{Name: "openssl", Architecture: "x86_64", NewVersion: "3.2-r0"}},
+		Removed:  []ApkoLockChange{{Name: "zlib", Architecture: "x86_64", OldVersion: "1.3-r0"}},
+		Upgraded: []ApkoLockChange{{Name: "curl", Architecture: "x86_64", OldVersion: "8.5-r0", NewVersion: "8.6-r0"}},
+		Downgraded: []ApkoLockChange{
+			{Name: "git", Architecture: "x86_64", OldVersion: "2.44.0-r1", NewVersion: "2.9.0-r0"},
+		},
+		Rebuilt: []ApkoLockChange{{Name: "glibc", Architecture: "x86_64", OldVersion: "2.39-r1", NewVersion: "2.39-r1"}},
+	}
+
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffApkoLocks() = %+v, want %+v", diff, want)
+	}
+
+	if !diff.HasChanges() {
+		t.Error("HasChanges() = false, want true")
+	}
+
+	summary := diff.String()
+	for _, line := range []string{"+ openssl", "- zlib", "~ curl (x86_64) 8.5-r0 -> 8.6-r0",
+		"< git (x86_64) 2.44.0-r1 -> 2.9.0-r0", "! glibc"} {
+		if !strings.Contains(summary, line) {
+			t.Errorf("String() = %q, want to contain %q", summary, line)
+		}
+	}
+
+	if DiffApkoLocks(previous, previous).HasChanges() {
+		t.Error("identical lockfiles must not report changes")
+	}
+}
+
+func TestCompareApkVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"2.0", "1.0", 1},
+		{"1.9", "1.10", -1},
+		{"1.2", "1.2.1", -1},
+		{"1.2_rc1", "1.2", -1},
+		{"1.2_alpha", "1.2_beta", -1},
+		{"1.2_p1", "1.2", 1},
+		{"1.2.1", "1.2_p1", 1},
+		{"1.2a", "1.2", 1},
+		{"1.2a", "1.2b", -1},
+		{"1.2-r1", "1.2-r0", 1},
+		{"1.2-r10", "1.2-r9", 1},
+		{"1.2-r0", "1.2", 0},
+		{"1.2~abc-r0", "1.2~def-r0", 0},
+		{"20240101", "20231231", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareApkVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareApkVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+
+		if got := compareApkVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareApkVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestApkoBuilderLockCommand(t *testing.T) {
+	cmd, err := NewApkoBuilder().
+		WithConfigFile("apko.yaml").
+		WithLockfile("/out/apko.lock.json").
+		WithBuildArch(ArchX8664).
+		LockCommand()
+	if err != nil {
+		t.Fatalf("LockCommand() error = %v", err)
+	}
+
+	expected := []string{"apko", "lock", "--arch", "x86_64", "--output", "/out/apko.lock.json", "apko.yaml"}
+	if !reflect.DeepEqual(cmd, expected) {
+		t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+	}
+}
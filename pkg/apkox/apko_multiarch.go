@@ -0,0 +1,234 @@
+package apkox
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// OCIImageIndexMediaType is the media type of an OCI image index.
+	OCIImageIndexMediaType = "application/vnd.oci.image.index.v1+json"
+	// OCIImageManifestMediaType is the media type of an OCI image manifest.
+	OCIImageManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// OCIPlatform describes the platform an image manifest was built for.
+type OCIPlatform struct {
+	// Architecture is the CPU architecture in GOARCH notation (e.g., "amd64", "arm64").
+	Architecture string `json:"architecture"`
+	// OS is the operating system (e.g., "linux").
+	OS string `json:"os"`
+	// Variant is the CPU variant (e.g., "v7" for arm).
+	Variant string `json:"variant,omitempty"`
+}
+
+// String returns the platform in "os/arch[/variant]" notation.
+func (p OCIPlatform) String() string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Architecture, p.Variant)
+	}
+
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// OCIDescriptor references a content-addressed blob, such as an image manifest.
+type OCIDescriptor struct {
+	// MediaType is the media type of the referenced content.
+	MediaType string `json:"mediaType"`
+	// Digest is the digest of the referenced content (e.g., "sha256:...").
+	Digest string `json:"digest"`
+	// Size is the size in bytes of the referenced content.
+	Size int64 `json:"size"`
+	// Platform is the platform of the referenced manifest, if any.
+	Platform *OCIPlatform `json:"platform,omitempty"`
+	// Annotations holds arbitrary metadata for the descriptor.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// OCIIndex is an OCI image index (manifest list) referencing one manifest per platform.
+type OCIIndex struct {
+	// SchemaVersion is always 2.
+	SchemaVersion int `json:"schemaVersion"`
+	// MediaType is the media type of the index.
+	MediaType string `json:"mediaType,omitempty"`
+	// Manifests lists the per-platform manifests.
+	Manifests []OCIDescriptor `json:"manifests"`
+	// Annotations holds arbitrary metadata for the index.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// JSON returns the indented JSON encoding of the index.
+func (i *OCIIndex) JSON() ([]byte, error) {
+	return json.MarshalIndent(i, "", "  ")
+}
+
+// ArchOutput describes the image produced for a single architecture of an APKO build.
+type ArchOutput struct {
+	// Arch is the APKO architecture.
+	Arch Architecture
+	// Platform is the OCI platform of the image.
+	Platform OCIPlatform
+	// ImageRef is the image reference the image is tagged with.
+	ImageRef string
+	// TarballPath is the tarball that holds the image.
+	TarballPath string
+	// SBOMPaths lists the SBOM files generated for the architecture.
+	SBOMPaths []string
+}
+
+// Platform returns the OCI platform that corresponds to the APKO architecture.
+// It returns an error if the architecture is not supported.
+//
+// Example:
+//
+//	p, _ := ArchArmv7.Platform()
+//	fmt.Println(p.String()) // Output: linux/arm/v7
+func (a Architecture) Platform() (OCIPlatform, error) {
+	switch a {
+	case ArchX8664:
+		return OCIPlatform{OS: "linux", Architecture: "amd64"}, nil
+	case ArchAarch64:
+		return OCIPlatform{OS: "linux", Architecture: "arm64"}, nil
+	case ArchArmv7:
+		return OCIPlatform{OS: "linux", Architecture: "arm", Variant: "v7"}, nil
+	case ArchPpc64le:
+		return OCIPlatform{OS: "linux", Architecture: "ppc64le"}, nil
+	case ArchS390x:
+		return OCIPlatform{OS: "linux", Architecture: "s390x"}, nil
+	default:
+		return OCIPlatform{}, fmt.Errorf("unsupported architecture: %s", a)
+	}
+}
+
+// ArchOutputs describes the per-architecture images produced by the build command.
+// apko writes a single OCI tarball holding one image per architecture, referenced from an image index;
+// each ArchOutput points to that tarball together with the platform to select and, when SBOM generation
+// is enabled and an SBOM path is configured, the per-architecture SBOM files (sbom-<arch>.<format>.json).
+// It returns an error if required fields are missing or an architecture is not supported.
+func (b *ApkoBuilder) ArchOutputs() ([]ArchOutput, error) {
+	if b.outputImage == "" {
+		return nil, fmt.Errorf("output image name is required")
+	}
+
+	if b.outputTarball == "" {
+		return nil, fmt.Errorf("output tarball path is required")
+	}
+
+	tag := b.tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	outputs := make([]ArchOutput, 0, len(b.Architectures()))
+
+	for _, arch := range b.Architectures() {
+		platform, err := arch.Platform()
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, ArchOutput{
+			Arch:        arch,
+			Platform:    platform,
+			ImageRef:    fmt.Sprintf("%s:%s", b.outputImage, tag),
+			TarballPath: b.outputTarball,
+			SBOMPaths:   b.sbomPathsFor(string(arch)),
+		})
+	}
+
+	return outputs, nil
+}
+
+// IndexSBOMPaths returns the SBOM files apko generates for the image index of a multi-arch build.
+// It returns nil when SBOM generation is disabled, no SBOM path is configured, or a single
+// architecture is targeted.
+func (b *ApkoBuilder) IndexSBOMPaths() []string {
+	if len(b.Architectures()) < 2 {
+		return nil
+	}
+
+	return b.sbomPathsFor("index")
+}
+
+// sbomPathsFor returns the SBOM file paths for the given SBOM subject (an architecture or "index").
+func (b *ApkoBuilder) sbomPathsFor(subject string) []string {
+	if !b.sbom || b.sbomPath == "" {
+		return nil
+	}
+
+	formats := b.sbomFormats
+	if len(formats) == 0 {
+		formats = []string{"spdx"}
+	}
+
+	paths := make([]string, 0, len(formats))
+	for _, format := range formats {
+		paths = append(paths, filepath.Join(b.sbomPath, fmt.Sprintf("sbom-%s.%s.json", subject, sbomExtension(format))))
+	}
+
+	return paths
+}
+
+// sbomExtension returns the file extension prefix used by apko for an SBOM format.
+func sbomExtension(format string) string {
+	if strings.EqualFold(format, "cyclonedx") {
+		return "cdx"
+	}
+
+	return strings.ToLower(format)
+}
+
+// BuildOCIIndex assembles an OCI image index from the per-architecture manifest descriptors.
+// The platform of each descriptor is derived from its architecture, the media type defaults to the
+// OCI image manifest media type, and manifests are ordered following SupportedArchitectures so the
+// resulting index is deterministic.
+//
+// Example:
+//
+//	index, err := BuildOCIIndex(map[Architecture]OCIDescriptor{
+//	    ArchX8664:   {Digest: "sha256:...", Size: 1024},
+//	    ArchAarch64: {Digest: "sha256:...", Size: 1024},
+//	})
+func BuildOCIIndex(manifests map[Architecture]OCIDescriptor) (*OCIIndex, error) {
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("at least one manifest is required to build an image index")
+	}
+
+	for arch := range manifests {
+		if !arch.IsValid() {
+			return nil, fmt.Errorf("unsupported architecture: %s", arch)
+		}
+	}
+
+	index := &OCIIndex{
+		SchemaVersion: 2,
+		MediaType:     OCIImageIndexMediaType,
+	}
+
+	for _, arch := range SupportedArchitectures() {
+		desc, ok := manifests[arch]
+		if !ok {
+			continue
+		}
+
+		if desc.Digest == "" {
+			return nil, fmt.Errorf("manifest digest is required for architecture %s", arch)
+		}
+
+		if desc.MediaType == "" {
+			desc.MediaType = OCIImageManifestMediaType
+		}
+
+		platform, err := arch.Platform()
+		if err != nil {
+			return nil, err
+		}
+
+		desc.Platform = &platform
+		index.Manifests = append(index.Manifests, desc)
+	}
+
+	return index, nil
+}
@@ -0,0 +1,179 @@
+package apkox
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestArchitecturePlatform(t *testing.T) {
+	tests := []struct {
+		arch    Architecture
+		want    string
+		wantErr bool
+	}{
+		{arch: ArchX8664, want: "linux/amd64"},
+		{arch: ArchAarch64, want: "linux/arm64"},
+		{arch: ArchArmv7, want: "linux/arm/v7"},
+		{arch: ArchPpc64le, want: "linux/ppc64le"},
+		{arch: ArchS390x, want: "linux/s390x"},
+		{arch: "mips", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.arch), func(t *testing.T) {
+			got, err := tt.arch.Platform()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Platform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("Platform() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestApkoBuilderMultiArchCommand(t *testing.T) {
+	t.Run("Single command for multiple architectures", func(t *testing.T) {
+		cmd, err := NewApkoBuilder().
+			WithConfigFile("config.yaml").
+			WithOutputImage("my-image").
+			WithOutputTarball("output.tar").
+			WithSBOM(true).
+			WithVCS(true).
+			WithBuildArchs(ArchX8664, ArchAarch64, ArchX8664).
+			BuildCommand()
+		if err != nil {
+			t.Fatalf("BuildCommand returned unexpected error: %v", err)
+		}
+
+		expected := []string{
+			"apko", "build",
+			"--arch", "x86_64,aarch64",
+			"config.yaml", "my-image:latest", "output.tar",
+		}
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("Single arch combined with arch set", func(t *testing.T) {
+		builder := NewApkoBuilder().WithBuildArch(ArchArmv7).WithBuildArchs(ArchArmv7, ArchS390x)
+
+		want := []Architecture{ArchArmv7, ArchS390x}
+		if got := builder.Architectures(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Architectures() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Unsupported architecture", func(t *testing.T) {
+		_, err := NewApkoBuilder().
+			WithConfigFile("config.yaml").
+			WithOutputImage("my-image").
+			WithOutputTarball("output.tar").
+			WithBuildArchs("mips").
+			BuildCommand()
+		if err == nil {
+			t.Error("Expected error for unsupported architecture")
+		}
+	})
+}
+
+func TestApkoBuilderArchOutputs(t *testing.T) {
+	builder := NewApkoBuilder().
+		WithOutputImage("my-image").
+		WithTag("v1").
+		WithOutputTarball("/out/image.tar").
+		WithSBOM(true).
+		WithSBOMPath("/out/sbom").
+		WithBuildArchs(ArchX8664, ArchArmv7)
+
+	outputs, err := builder.ArchOutputs()
+	if err != nil {
+		t.Fatalf("ArchOutputs() error = %v", err)
+	}
+
+	want := []ArchOutput{
+		{
+			Arch:        ArchX8664,
+			Platform:    OCIPlatform{OS: "linux", Architecture: "amd64"},
+			ImageRef:    "my-image:v1",
+			TarballPath: "/out/image.tar",
+			SBOMPaths:   []string{"/out/sbom/sbom-x86_64.spdx.json"},
+		},
+		{
+			Arch:        ArchArmv7,
+			Platform:    OCIPlatform{OS: "linux", Architecture: "arm", Variant: "v7"},
+			ImageRef:    "my-image:v1",
+			TarballPath: "/out/image.tar",
+			SBOMPaths:   []string{"/out/sbom/sbom-armv7.spdx.json"},
+		},
+	}
+
+	if !reflect.DeepEqual(outputs, want) {
+		t.Errorf("ArchOutputs() = %+v, want %+v", outputs, want)
+	}
+
+	if got := builder.IndexSBOMPaths(); !reflect.DeepEqual(got, []string{"/out/sbom/sbom-index.spdx.json"}) {
+		t.Errorf("IndexSBOMPaths() = %v", got)
+	}
+
+	if got := builder.WithSBOM(false).IndexSBOMPaths(); got != nil {
+		t.Errorf("IndexSBOMPaths() with SBOM disabled = %v, want nil", got)
+	}
+
+	if _, err := NewApkoBuilder().WithOutputImage("my-image").ArchOutputs(); err == nil {
+		t.Error("ArchOutputs() expected error for missing output tarball")
+	}
+}
+
+func TestBuildOCIIndex(t *testing.T) {
+	index, err := BuildOCIIndex(map[Architecture]OCIDescriptor{
+		ArchAarch64: {Digest: "sha256:bbb", Size: 200},
+		ArchX8664:   {Digest: "sha256:aaa", Size: 100},
+	})
+	if err != nil {
+		t.Fatalf("BuildOCIIndex() error = %v", err)
+	}
+
+	data, err := index.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+
+	var decoded OCIIndex
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode index: %v", err)
+	}
+
+	if decoded.SchemaVersion != 2 || decoded.MediaType != OCIImageIndexMediaType {
+		t.Errorf("unexpected index header: %+v", decoded)
+	}
+
+	if len(decoded.Manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(decoded.Manifests))
+	}
+
+	first := decoded.Manifests[0]
+	if first.Digest != "sha256:aaa" || first.MediaType != OCIImageManifestMediaType || first.Platform.Architecture != "amd64" {
+		t.Errorf("unexpected first manifest: %+v", first)
+	}
+
+	if decoded.Manifests[1].Platform.Architecture != "arm64" {
+		t.Errorf("unexpected second manifest: %+v", decoded.Manifests[1])
+	}
+
+	if _, err := BuildOCIIndex(nil); err == nil {
+		t.Error("BuildOCIIndex() expected error for empty manifests")
+	}
+
+	if _, err := BuildOCIIndex(map[Architecture]OCIDescriptor{ArchX8664: {}}); err == nil {
+		t.Error("BuildOCIIndex() expected error for missing digest")
+	}
+
+	if _, err := BuildOCIIndex(map[Architecture]OCIDescriptor{"mips": {Digest: "sha256:aaa"}}); err == nil {
+		t.Error("BuildOCIIndex() expected error for unsupported architecture")
+	}
+}
@@ -0,0 +1,220 @@
+package apkox
+
+import (
+	"fmt"
+
+	"github.com/Excoriate/daggerx/pkg/containerx"
+)
+
+// ApkoPublishBuilder represents a builder for the "apko publish", "apko show-config" and "apko lock"
+// subcommands. It shares the keyring, repository, architecture and cache options of an ApkoBuilder,
+// so the same base configuration can be used to build, publish, inspect and lock an image.
+type ApkoPublishBuilder struct {
+	// base holds the shared keyring, repository, architecture and cache options.
+	base *ApkoBuilder
+
+	// tags is the list of image references to publish the image to.
+	tags []string
+
+	// imageRefsFile is the path where apko writes the published image references.
+	imageRefsFile string
+
+	// sbom enables SBOM generation and attachment.
+	sbom bool
+
+	// sbomPath is the directory where the generated SBOMs are written.
+	sbomPath string
+
+	// sbomFormats is the list of SBOM formats to generate.
+	sbomFormats []string
+
+	// local publishes the image to the local Docker daemon instead of a registry.
+	local bool
+
+	// extraArgs is a slice of additional arguments to pass to the apko command.
+	extraArgs []string
+}
+
+// NewApkoPublishBuilder creates a new ApkoPublishBuilder that shares the options of the given ApkoBuilder.
+// The configuration file, keyrings, repositories, architectures and cache directory of 'base' are reused,
+// and the SBOM options of 'base' are the defaults of the publish command, so both build the same SBOMs.
+// 'base' is copied: configuring the ApkoPublishBuilder does not change it, and later changes to it
+// are not seen by the ApkoPublishBuilder. If 'base' is nil, a new empty ApkoBuilder is used.
+//
+// Example:
+//
+//	base := NewApkoBuilder().
+//	    WithConfigFile("apko.yaml").
+//	    WithKeyRingWolfi().
+//	    WithBuildArchs(ArchX8664, ArchAarch64)
+//
+//	cmd, err := NewApkoPublishBuilder(base).
+//	    WithTags("ghcr.io/org/app:v1.0.0").
+//	    WithImageRefsFile("/out/image-refs").
+//	    PublishCommand()
+func NewApkoPublishBuilder(base *ApkoBuilder) *ApkoPublishBuilder {
+	if base == nil {
+		base = NewApkoBuilder()
+	}
+
+	return &ApkoPublishBuilder{
+		base:        base.clone(),
+		sbom:        base.sbom,
+		sbomPath:    base.sbomPath,
+		sbomFormats: append([]string(nil), base.sbomFormats...),
+	}
+}
+
+// WithConfigFile sets the configuration file used by the publish, show-config and lock subcommands.
+// The ApkoBuilder the publish builder was created from is not changed.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithConfigFile(configFile string) *ApkoPublishBuilder {
+	p.base.WithConfigFile(configFile)
+	return p
+}
+
+// WithTags adds image references (e.g., "ghcr.io/org/app:v1.0.0") to publish the image to.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithTags(tags ...string) *ApkoPublishBuilder {
+	p.tags = append(p.tags, tags...)
+	return p
+}
+
+// WithImageRefsFile sets the file where apko writes the references of the published images.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithImageRefsFile(path string) *ApkoPublishBuilder {
+	p.imageRefsFile = path
+	return p
+}
+
+// WithSBOM enables or disables SBOM generation and attachment.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithSBOM(enable bool) *ApkoPublishBuilder {
+	p.sbom = enable
+	return p
+}
+
+// WithSBOMPath sets the directory where the generated SBOMs are written.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithSBOMPath(path string) *ApkoPublishBuilder {
+	p.sbomPath = path
+	return p
+}
+
+// WithSBOMFormats sets the SBOM formats to generate.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithSBOMFormats(formats ...string) *ApkoPublishBuilder {
+	p.sbomFormats = formats
+	return p
+}
+
+// WithLocal publishes the image to the local Docker daemon instead of a registry.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithLocal() *ApkoPublishBuilder {
+	p.local = true
+	return p
+}
+
+// WithExtraArg adds an extra argument to the generated commands.
+// It returns the updated ApkoPublishBuilder instance.
+func (p *ApkoPublishBuilder) WithExtraArg(arg string) *ApkoPublishBuilder {
+	p.extraArgs = append(p.extraArgs, arg)
+	return p
+}
+
+// PublishCommand generates the "apko publish" command.
+// It requires a configuration file and at least one valid image reference (see containerx.Parse) to publish to.
+// It returns a slice of strings representing the command and an error if the configuration is invalid.
+func (p *ApkoPublishBuilder) PublishCommand() ([]string, error) {
+	if err := p.validateConfigFile(); err != nil {
+		return nil, err
+	}
+
+	if len(p.tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required to publish")
+	}
+
+	for _, tag := range p.tags {
+		if _, err := containerx.Parse(tag); err != nil {
+			return nil, fmt.Errorf("invalid tag %s: %w", tag, err)
+		}
+	}
+
+	cmd := []string{"apko", "publish"}
+	cmd = append(cmd, p.base.sharedFlags()...)
+
+	if !p.sbom {
+		cmd = append(cmd, "--sbom=false")
+	}
+
+	if p.sbomPath != "" {
+		cmd = append(cmd, "--sbom-path", p.sbomPath)
+	}
+
+	for _, f := range p.sbomFormats {
+		cmd = append(cmd, "--sbom-formats", f)
+	}
+
+	if p.imageRefsFile != "" {
+		cmd = append(cmd, "--image-refs", p.imageRefsFile)
+	}
+
+	if p.local {
+		cmd = append(cmd, "--local")
+	}
+
+	cmd = append(cmd, p.base.configFile)
+	cmd = append(cmd, p.tags...)
+
+	return append(cmd, p.extraArgs...), nil
+}
+
+// ShowConfigCommand generates the "apko show-config" command, which prints the resolved configuration.
+// It returns a slice of strings representing the command and an error if the configuration file is invalid.
+func (p *ApkoPublishBuilder) ShowConfigCommand() ([]string, error) {
+	if err := p.validateConfigFile(); err != nil {
+		return nil, err
+	}
+
+	cmd := []string{"apko", "show-config"}
+	cmd = append(cmd, p.base.sharedFlags()...)
+	cmd = append(cmd, p.base.configFile)
+
+	return append(cmd, p.extraArgs...), nil
+}
+
+// LockCommand generates the "apko lock" command, which resolves the packages of the configuration
+// and writes them to a lockfile. If 'output' is empty, apko writes the lockfile next to the configuration.
+// It returns a slice of strings representing the command and an error if the configuration file is invalid.
+func (p *ApkoPublishBuilder) LockCommand(output string) ([]string, error) {
+	if err := p.validateConfigFile(); err != nil {
+		return nil, err
+	}
+
+	cmd := []string{"apko", "lock"}
+	cmd = append(cmd, p.base.sharedFlags()...)
+
+	if output != "" {
+		cmd = append(cmd, "--output", output)
+	}
+
+	cmd = append(cmd, p.base.configFile)
+
+	return append(cmd, p.extraArgs...), nil
+}
+
+// validateConfigFile checks that the shared configuration file is set and has a YAML extension,
+// that the shared architectures are supported and that the keyring presets are registered.
+func (p *ApkoPublishBuilder) validateConfigFile() error {
+	if _, err := GetApkoConfigOrPreset("", p.base.configFile); err != nil {
+		return err
+	}
+
+	if err := p.base.validateArchs(); err != nil {
+		return err
+	}
+
+	_, err := p.base.resolveKeyringPresets()
+
+	return err
+}
@@ -0,0 +1,198 @@
+package apkox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApkoPublishBuilder(t *testing.T) {
+	base := func() *ApkoBuilder {
+		return NewApkoBuilder().
+			WithConfigFile("apko.yaml").
+			WithCacheDir("/cache").
+			WithKeyRingWolfi().
+			WithBuildArchs(ArchX8664, ArchAarch64).
+			WithRepositoryAppend("https://packages.wolfi.dev/os")
+	}
+
+	shared := []string{
+		"--cache-dir", "/cache",
+		"--keyring-append", ApkoWolfiSigninRsaKeyPath,
+		"--arch", "x86_64,aarch64",
+		"--repository-append", "https://packages.wolfi.dev/os",
+	}
+
+	t.Run("PublishCommand", func(t *testing.T) {
+		cmd, err := NewApkoPublishBuilder(base()).
+			WithTags("ghcr.io/org/app:v1.0.0", "ghcr.io/org/app:latest").
+			WithSBOM(true).
+			WithSBOMPath("/out/sbom").
+			WithSBOMFormats("spdx").
+			WithImageRefsFile("/out/image-refs").
+			PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected := append([]string{"apko", "publish"}, shared...)
+		expected = append(expected,
+			"--sbom-path", "/out/sbom",
+			"--sbom-formats", "spdx",
+			"--image-refs", "/out/image-refs",
+			"apko.yaml",
+			"ghcr.io/org/app:v1.0.0",
+			"ghcr.io/org/app:latest",
+		)
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("PublishCommand_LocalWithoutSBOM", func(t *testing.T) {
+		cmd, err := NewApkoPublishBuilder(nil).
+			WithConfigFile("apko.yml").
+			WithTags("app:dev").
+			WithLocal().
+			WithExtraArg("--debug").
+			PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected := []string{"apko", "publish", "--sbom=false", "--local", "apko.yml", "app:dev", "--debug"}
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("PublishCommand_RegistryWithPortAndNestedPath", func(t *testing.T) {
+		cmd, err := NewApkoPublishBuilder(nil).
+			WithConfigFile("apko.yaml").
+			WithTags("localhost:5000/app:v1", "registry.example.com/team/group/sub/app:v1").
+			PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected := []string{
+			"apko", "publish", "--sbom=false", "apko.yaml",
+			"localhost:5000/app:v1", "registry.example.com/team/group/sub/app:v1",
+		}
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("PublishCommand_Errors", func(t *testing.T) {
+		if _, err := NewApkoPublishBuilder(nil).WithTags("app:v1").PublishCommand(); err == nil {
+			t.Error("expected error for missing config file")
+		}
+
+		if _, err := NewApkoPublishBuilder(base()).PublishCommand(); err == nil {
+			t.Error("expected error for missing tags")
+		}
+
+		if _, err := NewApkoPublishBuilder(base()).WithTags("app:bad tag").PublishCommand(); err == nil {
+			t.Error("expected error for invalid tag")
+		}
+
+		if _, err := NewApkoPublishBuilder(base().WithBuildArchs("mips")).WithTags("app:v1").PublishCommand(); err == nil {
+			t.Error("expected error for unsupported architecture")
+		}
+	})
+
+	t.Run("PublishCommand_DoesNotChangeBase", func(t *testing.T) {
+		b := base().WithOutputImage("app").WithOutputTarball("/out/app.tar")
+
+		before, err := b.BuildCommand()
+		if err != nil {
+			t.Fatalf("BuildCommand() error = %v", err)
+		}
+
+		publish := NewApkoPublishBuilder(b).WithConfigFile("other.yaml").WithTags("app:v1")
+
+		after, err := b.BuildCommand()
+		if err != nil {
+			t.Fatalf("BuildCommand() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(before, after) {
+			t.Errorf("base command changed.\nBefore: %v\nAfter:  %v", before, after)
+		}
+
+		b.WithConfigFile("later.yaml").WithCacheDir("/later")
+
+		cmd, err := publish.PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected := append([]string{"apko", "publish"}, shared...)
+		expected = append(expected, "--sbom=false", "other.yaml", "app:v1")
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("PublishCommand_SBOMDefaultsFromBase", func(t *testing.T) {
+		b := base().WithSBOM(true).WithSBOMPath("/out/sbom").WithSBOMFormats("spdx")
+
+		cmd, err := NewApkoPublishBuilder(b).WithTags("app:v1").PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected := append([]string{"apko", "publish"}, shared...)
+		expected = append(expected, "--sbom-path", "/out/sbom", "--sbom-formats", "spdx", "apko.yaml", "app:v1")
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+
+		cmd, err = NewApkoPublishBuilder(b).WithSBOM(false).WithTags("app:v1").PublishCommand()
+		if err != nil {
+			t.Fatalf("PublishCommand() error = %v", err)
+		}
+
+		expected = append([]string{"apko", "publish"}, shared...)
+		expected = append(expected, "--sbom=false", "--sbom-path", "/out/sbom", "--sbom-formats", "spdx", "apko.yaml", "app:v1")
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("ShowConfigCommand", func(t *testing.T) {
+		cmd, err := NewApkoPublishBuilder(base()).ShowConfigCommand()
+		if err != nil {
+			t.Fatalf("ShowConfigCommand() error = %v", err)
+		}
+
+		expected := append([]string{"apko", "show-config"}, shared...)
+		expected = append(expected, "apko.yaml")
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+	})
+
+	t.Run("LockCommand", func(t *testing.T) {
+		cmd, err := NewApkoPublishBuilder(base()).LockCommand("apko.lock.json")
+		if err != nil {
+			t.Fatalf("LockCommand() error = %v", err)
+		}
+
+		expected := append([]string{"apko", "lock"}, shared...)
+		expected = append(expected, "--output", "apko.lock.json", "apko.yaml")
+
+		if !reflect.DeepEqual(cmd, expected) {
+			t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+		}
+
+		if _, err := NewApkoPublishBuilder(nil).WithConfigFile("apko.json").LockCommand(""); err == nil {
+			t.Error("expected error for invalid config extension")
+		}
+	})
+}
@@ -0,0 +1,437 @@
+package apkox
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// maxMetadataBlobSize is the largest blob read into memory when inspecting a build tarball.
+// Manifests, configs and indexes are far smaller; larger blobs are layers and only their size is recorded.
+const maxMetadataBlobSize = 4 << 20
+
+// ImageConfig is the subset of the OCI image configuration produced by apko.
+type ImageConfig struct {
+	// Architecture is the CPU architecture of the image.
+	Architecture string `json:"architecture"`
+	// OS is the operating system of the image.
+	OS string `json:"os"`
+	// Variant is the CPU variant of the image.
+	Variant string `json:"variant,omitempty"`
+	// Created is the creation date of the image.
+	Created string `json:"created,omitempty"`
+	// Config holds the runtime configuration of the image.
+	Config ImageRuntimeConfig `json:"config"`
+}
+
+// ImageRuntimeConfig is the runtime configuration of an image.
+type ImageRuntimeConfig struct {
+	// User is the user the image runs as.
+	User string `json:"User,omitempty"`
+	// Env is the list of environment variables in "KEY=value" format.
+	Env []string `json:"Env,omitempty"`
+	// Entrypoint is the entrypoint of the image.
+	Entrypoint []string `json:"Entrypoint,omitempty"`
+	// Cmd holds the default arguments of the entrypoint.
+	Cmd []string `json:"Cmd,omitempty"`
+	// WorkingDir is the working directory of the image.
+	WorkingDir string `json:"WorkingDir,omitempty"`
+	// Labels holds the labels of the image.
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+// ImageMetadata describes a single image of an apko build tarball.
+type ImageMetadata struct {
+	// Digest is the manifest digest of the image. Docker tarballs do not store the manifest, so its
+	// digest is computed from the Docker image manifest rebuilt from the configuration and layers,
+	// as registries and go-containerregistry build it.
+	Digest string
+	// Platform is the platform of the image.
+	Platform OCIPlatform
+	// ConfigDigest is the digest of the image configuration.
+	ConfigDigest string
+	// Config is the image configuration.
+	Config ImageConfig
+	// Layers lists the layer descriptors of the image.
+	Layers []OCIDescriptor
+	// Size is the total size in bytes of the manifest, configuration and layers.
+	Size int64
+}
+
+// BuildResult describes the content of an apko build tarball.
+type BuildResult struct {
+	// IndexDigest is the digest of the OCI image index. It is empty when the tarball holds a single image.
+	IndexDigest string
+	// Images lists the images of the tarball, one per architecture.
+	Images []ImageMetadata
+}
+
+// Image returns the image built for the given architecture, and whether it was found.
+func (r *BuildResult) Image(arch Architecture) (ImageMetadata, bool) {
+	platform, err := arch.Platform()
+	if err != nil {
+		return ImageMetadata{}, false
+	}
+
+	for _, img := range r.Images {
+		if img.Platform == platform {
+			return img, true
+		}
+	}
+
+	return ImageMetadata{}, false
+}
+
+// SizesByArch returns the total size of each image, keyed by platform ("os/arch[/variant]").
+func (r *BuildResult) SizesByArch() map[string]int64 {
+	sizes := make(map[string]int64, len(r.Images))
+	for _, img := range r.Images {
+		sizes[img.Platform.String()] = img.Size
+	}
+
+	return sizes
+}
+
+// ociManifest is an OCI image manifest.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    OCIDescriptor   `json:"config"`
+	Layers    []OCIDescriptor `json:"layers"`
+}
+
+// dockerManifestEntry is an entry of the manifest.json file of a Docker tarball.
+type dockerManifestEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// dockerManifest is a Docker image manifest (schema 2), as rebuilt from a Docker tarball.
+// Its fields are in the order registries serialize them, so that its digest matches theirs.
+type dockerManifest struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	MediaType     string             `json:"mediaType"`
+	Config        dockerDescriptor   `json:"config"`
+	Layers        []dockerDescriptor `json:"layers"`
+}
+
+// dockerDescriptor is a descriptor of a Docker image manifest.
+type dockerDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Digest    string `json:"digest"`
+}
+
+// Media types of the Docker image manifest rebuilt from a Docker tarball.
+const (
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerConfigMediaType   = "application/vnd.docker.container.image.v1+json"
+	dockerLayerMediaType    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+)
+
+// tarballContents holds the entries of a build tarball: the size, digest and compression of every file,
+// and the content of the files small enough to be metadata.
+type tarballContents struct {
+	sizes   map[string]int64
+	digests map[string]string
+	gzipped map[string]bool
+	files   map[string][]byte
+}
+
+// ReadBuildResult opens the apko build tarball at the given path (see GetOutputTarPath) and
+// returns the manifest digests, configurations, layers and sizes of the images it holds.
+// Both OCI layout tarballs (multi-arch builds) and Docker tarballs are supported, plain or gzipped.
+//
+// Example:
+//
+//	result, err := ReadBuildResult(GetOutputTarPath("/mnt"))
+//	if err != nil {
+//	    // handle error
+//	}
+//	for _, img := range result.Images {
+//	    fmt.Println(img.Platform.String(), img.Digest, img.Size)
+//	}
+func ReadBuildResult(tarPath string) (*BuildResult, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open build tarball %s: %w", tarPath, err)
+	}
+	defer f.Close()
+
+	return ReadBuildResultFromReader(f)
+}
+
+// ReadBuildResultFromReader reads an apko build tarball from the given reader.
+// See ReadBuildResult for details.
+func ReadBuildResultFromReader(r io.Reader) (*BuildResult, error) {
+	contents, err := readTarball(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := contents.files["index.json"]; ok {
+		return contents.ociLayoutResult()
+	}
+
+	if _, ok := contents.files["manifest.json"]; ok {
+		return contents.dockerResult()
+	}
+
+	return nil, errors.New("build tarball holds neither an OCI layout nor a Docker manifest")
+}
+
+// readTarball reads the entries of a plain or gzipped tarball.
+func readTarball(r io.Reader) (*tarballContents, error) {
+	br := bufio.NewReader(r)
+
+	var reader io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzipped build tarball: %w", err)
+		}
+		defer gz.Close()
+
+		reader = gz
+	}
+
+	contents := &tarballContents{
+		sizes:   map[string]int64{},
+		digests: map[string]string{},
+		gzipped: map[string]bool{},
+		files:   map[string][]byte{},
+	}
+	tr := tar.NewReader(reader)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read build tarball: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := tarEntryName(hdr.Name)
+		contents.sizes[name] = hdr.Size
+
+		// Every entry is hashed, but only the ones small enough to be metadata are kept in memory.
+		var (
+			buf    bytes.Buffer
+			header [2]byte
+			hash   = sha256.New()
+			w      = io.Writer(hash)
+		)
+
+		if hdr.Size <= maxMetadataBlobSize {
+			w = io.MultiWriter(hash, &buf)
+		}
+
+		n, err := io.ReadFull(tr, header[:])
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to read %s from build tarball: %w", name, err)
+		}
+
+		if _, err := io.Copy(w, io.MultiReader(bytes.NewReader(header[:n]), tr)); err != nil {
+			return nil, fmt.Errorf("failed to read %s from build tarball: %w", name, err)
+		}
+
+		contents.digests[name] = "sha256:" + hex.EncodeToString(hash.Sum(nil))
+		contents.gzipped[name] = n == 2 && header[0] == 0x1f && header[1] == 0x8b
+
+		if hdr.Size <= maxMetadataBlobSize {
+			contents.files[name] = buf.Bytes()
+		}
+	}
+
+	return contents, nil
+}
+
+// tarEntryName returns the tar entry name in the form the entries are looked up by:
+// relative, without "./" or "..", and with a single separator between components.
+func tarEntryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// blob returns the content of the blob with the given digest from an OCI layout.
+func (c *tarballContents) blob(digest string) ([]byte, error) {
+	algo, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid digest: %s", digest)
+	}
+
+	data, ok := c.files[tarEntryName(path.Join("blobs", algo, hex))]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found in build tarball", digest)
+	}
+
+	return data, nil
+}
+
+// ociLayoutResult builds the result from an OCI layout tarball. A top-level index pointing to
+// a nested image index (as written for multi-arch builds) is followed to the per-platform manifests.
+func (c *tarballContents) ociLayoutResult() (*BuildResult, error) {
+	var index OCIIndex
+	if err := json.Unmarshal(c.files["index.json"], &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index.json: %w", err)
+	}
+
+	result := &BuildResult{}
+
+	if len(index.Manifests) == 1 && index.Manifests[0].MediaType == OCIImageIndexMediaType {
+		result.IndexDigest = index.Manifests[0].Digest
+
+		data, err := c.blob(result.IndexDigest)
+		if err != nil {
+			return nil, err
+		}
+
+		index = OCIIndex{}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("failed to parse image index %s: %w", result.IndexDigest, err)
+		}
+	}
+
+	for _, desc := range index.Manifests {
+		img, err := c.ociImage(desc)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Images = append(result.Images, img)
+	}
+
+	return result, nil
+}
+
+// ociImage reads the manifest and configuration referenced by the descriptor.
+func (c *tarballContents) ociImage(desc OCIDescriptor) (ImageMetadata, error) {
+	data, err := c.blob(desc.Digest)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ImageMetadata{}, fmt.Errorf("failed to parse manifest %s: %w", desc.Digest, err)
+	}
+
+	cfgData, err := c.blob(manifest.Config.Digest)
+	if err != nil {
+		return ImageMetadata{}, err
+	}
+
+	var cfg ImageConfig
+	if err := json.Unmarshal(cfgData, &cfg); err != nil {
+		return ImageMetadata{}, fmt.Errorf("failed to parse config %s: %w", manifest.Config.Digest, err)
+	}
+
+	size := int64(len(data)) + manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	platform := OCIPlatform{OS: cfg.OS, Architecture: cfg.Architecture, Variant: cfg.Variant}
+	if desc.Platform != nil {
+		platform = *desc.Platform
+	}
+
+	return ImageMetadata{
+		Digest:       desc.Digest,
+		Platform:     platform,
+		ConfigDigest: manifest.Config.Digest,
+		Config:       cfg,
+		Layers:       manifest.Layers,
+		Size:         size,
+	}, nil
+}
+
+// dockerResult builds the result from a Docker tarball described by manifest.json.
+// The digests of the configuration and layers are computed from their content, and the manifest
+// digest from the Docker image manifest rebuilt from them. Layers must be gzipped, as apko writes them;
+// the manifest digest of an image with uncompressed layers cannot be known, so it is reported as an error.
+func (c *tarballContents) dockerResult() (*BuildResult, error) {
+	var entries []dockerManifestEntry
+	if err := json.Unmarshal(c.files["manifest.json"], &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest.json: %w", err)
+	}
+
+	result := &BuildResult{}
+
+	for _, entry := range entries {
+		cfgData, ok := c.files[tarEntryName(entry.Config)]
+		if !ok {
+			return nil, fmt.Errorf("config %s not found in build tarball", entry.Config)
+		}
+
+		var cfg ImageConfig
+		if err := json.Unmarshal(cfgData, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", entry.Config, err)
+		}
+
+		manifest := dockerManifest{
+			SchemaVersion: 2,
+			MediaType:     dockerManifestMediaType,
+			Config: dockerDescriptor{
+				MediaType: dockerConfigMediaType,
+				Size:      int64(len(cfgData)),
+				Digest:    c.digests[tarEntryName(entry.Config)],
+			},
+			Layers: []dockerDescriptor{},
+		}
+
+		img := ImageMetadata{
+			Platform:     OCIPlatform{OS: cfg.OS, Architecture: cfg.Architecture, Variant: cfg.Variant},
+			ConfigDigest: manifest.Config.Digest,
+			Config:       cfg,
+			Size:         manifest.Config.Size,
+		}
+
+		for _, layer := range entry.Layers {
+			name := tarEntryName(layer)
+
+			size, ok := c.sizes[name]
+			if !ok {
+				return nil, fmt.Errorf("layer %s not found in build tarball", layer)
+			}
+
+			if !c.gzipped[name] {
+				return nil, fmt.Errorf("layer %s is not gzipped: the manifest digest cannot be computed", layer)
+			}
+
+			desc := dockerDescriptor{MediaType: dockerLayerMediaType, Size: size, Digest: c.digests[name]}
+			manifest.Layers = append(manifest.Layers, desc)
+
+			img.Layers = append(img.Layers, OCIDescriptor{MediaType: desc.MediaType, Digest: desc.Digest, Size: size})
+			img.Size += size
+		}
+
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the manifest of %s: %w", entry.Config, err)
+		}
+
+		sum := sha256.Sum256(data)
+		img.Digest = "sha256:" + hex.EncodeToString(sum[:])
+		img.Size += int64(len(data))
+
+		result.Images = append(result.Images, img)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,324 @@
+package apkox
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testTarEntry struct {
+	name string
+	data []byte
+}
+
+func writeTestTar(t *testing.T, entries []testTarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// buildTestOCILayout returns an OCI layout tarball with a nested image index holding one image per architecture.
+func buildTestOCILayout(t *testing.T, archs ...Architecture) ([]byte, map[Architecture]string, string) {
+	t.Helper()
+
+	var entries []testTarEntry
+
+	addBlob := func(data []byte) string {
+		d := testDigest(data)
+		entries = append(entries, testTarEntry{name: "blobs/sha256/" + d[len("sha256:"):], data: data})
+
+		return d
+	}
+
+	manifests := map[Architecture]OCIDescriptor{}
+	digests := map[Architecture]string{}
+
+	for _, arch := range archs {
+		platform, err := arch.Platform()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layer := bytes.Repeat([]byte{byte(len(arch))}, 100)
+		cfg := mustJSON(t, ImageConfig{
+			Architecture: platform.Architecture,
+			OS:           platform.OS,
+			Variant:      platform.Variant,
+			Config:       ImageRuntimeConfig{Entrypoint: []string{"/bin/sh"}, Env: []string{"PATH=/bin"}},
+		})
+
+		manifest := mustJSON(t, ociManifest{
+			MediaType: OCIImageManifestMediaType,
+			Config:    OCIDescriptor{Digest: addBlob(cfg), Size: int64(len(cfg))},
+			Layers:    []OCIDescriptor{{Digest: addBlob(layer), Size: int64(len(layer))}},
+		})
+
+		digests[arch] = addBlob(manifest)
+		manifests[arch] = OCIDescriptor{Digest: digests[arch], Size: int64(len(manifest))}
+	}
+
+	index, err := BuildOCIIndex(manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexDigest := addBlob(mustJSON(t, index))
+	top := mustJSON(t, OCIIndex{
+		SchemaVersion: 2,
+		Manifests:     []OCIDescriptor{{MediaType: OCIImageIndexMediaType, Digest: indexDigest}},
+	})
+
+	entries = append(entries,
+		testTarEntry{name: "oci-layout", data: []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		testTarEntry{name: "index.json", data: top},
+	)
+
+	return writeTestTar(t, entries), digests, indexDigest
+}
+
+func TestReadBuildResultOCILayout(t *testing.T) {
+	data, digests, indexDigest := buildTestOCILayout(t, ArchX8664, ArchAarch64)
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadBuildResult(path)
+	if err != nil {
+		t.Fatalf("ReadBuildResult() error = %v", err)
+	}
+
+	if result.IndexDigest != indexDigest {
+		t.Errorf("IndexDigest = %s, want %s", result.IndexDigest, indexDigest)
+	}
+
+	if len(result.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(result.Images))
+	}
+
+	img, ok := result.Image(ArchAarch64)
+	if !ok {
+		t.Fatal("Image() did not find aarch64")
+	}
+
+	if img.Digest != digests[ArchAarch64] {
+		t.Errorf("Digest = %s, want %s", img.Digest, digests[ArchAarch64])
+	}
+
+	if img.Config.Config.Entrypoint[0] != "/bin/sh" || len(img.Layers) != 1 || img.Layers[0].Size != 100 {
+		t.Errorf("unexpected image metadata: %+v", img)
+	}
+
+	sizes := result.SizesByArch()
+	if sizes["linux/amd64"] == 0 || sizes["linux/arm64"] != img.Size {
+		t.Errorf("SizesByArch() = %v", sizes)
+	}
+
+	if _, ok := result.Image(ArchS390x); ok {
+		t.Error("Image() found an architecture that was not built")
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestReadBuildResultGzipped(t *testing.T) {
+	data, _, _ := buildTestOCILayout(t, ArchArmv7)
+
+	result, err := ReadBuildResultFromReader(bytes.NewReader(gzipBytes(t, data)))
+	if err != nil {
+		t.Fatalf("ReadBuildResultFromReader() error = %v", err)
+	}
+
+	if _, ok := result.Image(ArchArmv7); !ok {
+		t.Errorf("Image() did not find armv7 in %+v", result.Images)
+	}
+}
+
+func TestReadBuildResultDotSlashEntries(t *testing.T) {
+	data, digests, _ := buildTestOCILayout(t, ArchX8664)
+
+	var entries []testTarEntry
+
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, testTarEntry{name: "./" + hdr.Name, data: content})
+	}
+
+	result, err := ReadBuildResultFromReader(bytes.NewReader(writeTestTar(t, entries)))
+	if err != nil {
+		t.Fatalf("ReadBuildResultFromReader() error = %v", err)
+	}
+
+	if img, ok := result.Image(ArchX8664); !ok || img.Digest != digests[ArchX8664] {
+		t.Errorf("Image() = %+v, want digest %s", img, digests[ArchX8664])
+	}
+}
+
+func TestReadBuildResultDockerTarball(t *testing.T) {
+	cfg := mustJSON(t, ImageConfig{Architecture: "amd64", OS: "linux"})
+	cfgDigest := testDigest(cfg)
+	layer := gzipBytes(t, bytes.Repeat([]byte{1}, 50))
+	layerDigest := testDigest(layer)
+
+	// The manifest go-containerregistry and registries build for the image.
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.v2+json",` +
+		`"config":{"mediaType":"application/vnd.docker.container.image.v1+json","size":` +
+		strconv.Itoa(len(cfg)) + `,"digest":"` + cfgDigest + `"},` +
+		`"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","size":` +
+		strconv.Itoa(len(layer)) + `,"digest":"` + layerDigest + `"}]}`
+
+	tests := []struct {
+		name        string
+		configName  string
+		layerName   string
+		configEntry string
+		layerEntry  string
+	}{
+		{name: "Digest file names", configName: cfgDigest, layerName: "abc123.tar.gz"},
+		{name: "Layer tar file names", configName: "config.json", layerName: "0/layer.tar"},
+		{
+			name: "Dot-slash entries", configName: "config.json", layerName: "0/layer.tar",
+			configEntry: "./config.json", layerEntry: "./0/layer.tar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configEntry, layerEntry := tt.configEntry, tt.layerEntry
+			if configEntry == "" {
+				configEntry, layerEntry = tt.configName, tt.layerName
+			}
+
+			data := writeTestTar(t, []testTarEntry{
+				{name: configEntry, data: cfg},
+				{name: layerEntry, data: layer},
+				{name: "manifest.json", data: mustJSON(t, []dockerManifestEntry{
+					{Config: tt.configName, RepoTags: []string{"my-image:latest"}, Layers: []string{tt.layerName}},
+				})},
+			})
+
+			result, err := ReadBuildResultFromReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadBuildResultFromReader() error = %v", err)
+			}
+
+			img, ok := result.Image(ArchX8664)
+			if !ok {
+				t.Fatal("Image() did not find x86_64")
+			}
+
+			if img.Digest != testDigest([]byte(manifest)) {
+				t.Errorf("Digest = %s, want %s", img.Digest, testDigest([]byte(manifest)))
+			}
+
+			if img.ConfigDigest != cfgDigest || img.Layers[0].Digest != layerDigest {
+				t.Errorf("unexpected digests: %+v", img)
+			}
+
+			if want := int64(len(manifest) + len(cfg) + len(layer)); img.Size != want {
+				t.Errorf("Size = %d, want %d", img.Size, want)
+			}
+		})
+	}
+}
+
+func TestReadBuildResultDockerTarballUncompressedLayer(t *testing.T) {
+	cfg := mustJSON(t, ImageConfig{Architecture: "amd64", OS: "linux"})
+
+	data := writeTestTar(t, []testTarEntry{
+		{name: "config.json", data: cfg},
+		{name: "layer.tar", data: bytes.Repeat([]byte{1}, 50)},
+		{name: "manifest.json", data: mustJSON(t, []dockerManifestEntry{
+			{Config: "config.json", Layers: []string{"layer.tar"}},
+		})},
+	})
+
+	_, err := ReadBuildResultFromReader(bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "is not gzipped") {
+		t.Errorf("ReadBuildResultFromReader() error = %v, want not gzipped error", err)
+	}
+}
+
+func TestReadBuildResultErrors(t *testing.T) {
+	if _, err := ReadBuildResult(filepath.Join(t.TempDir(), "missing.tar")); err == nil {
+		t.Error("expected error for missing tarball")
+	}
+
+	empty := writeTestTar(t, []testTarEntry{{name: "README", data: []byte("hi")}})
+	if _, err := ReadBuildResultFromReader(bytes.NewReader(empty)); err == nil {
+		t.Error("expected error for tarball without layout or manifest")
+	}
+
+	broken := writeTestTar(t, []testTarEntry{{name: "index.json", data: []byte(`{"manifests":[{"digest":"sha256:missing"}]}`)}})
+	if _, err := ReadBuildResultFromReader(bytes.NewReader(broken)); err == nil {
+		t.Error("expected error for missing manifest blob")
+	}
+}
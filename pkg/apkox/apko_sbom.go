@@ -0,0 +1,242 @@
+package apkox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SBOMFormat identifies the format of an SBOM document.
+type SBOMFormat string
+
+const (
+	// SBOMFormatSPDX is the SPDX JSON format.
+	SBOMFormatSPDX SBOMFormat = "spdx"
+	// SBOMFormatCycloneDX is the CycloneDX JSON format.
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+// SBOMPackage is a package listed in an SBOM.
+type SBOMPackage struct {
+	// Name is the package name.
+	Name string
+	// Version is the package version.
+	Version string
+	// PURL is the package URL (e.g., "pkg:apk/wolfi/busybox@1.36.1-r1?arch=x86_64").
+	PURL string
+	// License is the declared (or, if not declared, concluded) license expression.
+	License string
+	// Supplier is the supplier of the package.
+	Supplier string
+}
+
+// spdxDocument is the subset of an SPDX JSON document read by ParseSBOM.
+type spdxDocument struct {
+	SPDXVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		Supplier         string `json:"supplier"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		ExternalRefs     []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// cycloneDXDocument is the subset of a CycloneDX JSON document read by ParseSBOM.
+type cycloneDXDocument struct {
+	BOMFormat  string `json:"bomFormat"`
+	Components []struct {
+		Name      string `json:"name"`
+		Version   string `json:"version"`
+		PURL      string `json:"purl"`
+		Publisher string `json:"publisher"`
+		Supplier  struct {
+			Name string `json:"name"`
+		} `json:"supplier"`
+		Licenses []struct {
+			License struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"license"`
+			Expression string `json:"expression"`
+		} `json:"licenses"`
+	} `json:"components"`
+}
+
+// DetectSBOMFormat returns the format of the SBOM document.
+// It returns an error if the content is not a JSON SPDX or CycloneDX document.
+func DetectSBOMFormat(data []byte) (SBOMFormat, error) {
+	var probe struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("failed to parse SBOM: %w", err)
+	}
+
+	switch {
+	case probe.SPDXVersion != "":
+		return SBOMFormatSPDX, nil
+	case strings.EqualFold(probe.BOMFormat, "CycloneDX"):
+		return SBOMFormatCycloneDX, nil
+	default:
+		return "", errors.New("unknown SBOM format: expected SPDX or CycloneDX JSON")
+	}
+}
+
+// ParseSBOM parses an SPDX or CycloneDX JSON SBOM, as generated by apko, into a package list.
+// The format is detected from the document. Entries without a name are skipped.
+//
+// Example:
+//
+//	pkgs, err := LoadSBOM("/out/sbom/sbom-x86_64.spdx.json")
+//	if err != nil {
+//	    // handle error
+//	}
+//	for _, p := range pkgs {
+//	    fmt.Println(p.Name, p.Version)
+//	}
+func ParseSBOM(data []byte) ([]SBOMPackage, error) {
+	format, err := DetectSBOMFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == SBOMFormatCycloneDX {
+		return parseCycloneDX(data)
+	}
+
+	return parseSPDX(data)
+}
+
+// LoadSBOM reads and parses the SBOM file at the given path.
+func LoadSBOM(path string) ([]SBOMPackage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SBOM %s: %w", path, err)
+	}
+
+	pkgs, err := ParseSBOM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SBOM %s: %w", path, err)
+	}
+
+	return pkgs, nil
+}
+
+// LoadArchSBOMs loads the SBOMs of every architecture output (see ApkoBuilder.ArchOutputs).
+// Packages of several SBOM files of the same architecture are concatenated.
+func LoadArchSBOMs(outputs []ArchOutput) (map[Architecture][]SBOMPackage, error) {
+	sboms := make(map[Architecture][]SBOMPackage, len(outputs))
+
+	for _, out := range outputs {
+		for _, p := range out.SBOMPaths {
+			pkgs, err := LoadSBOM(p)
+			if err != nil {
+				return nil, err
+			}
+
+			sboms[out.Arch] = append(sboms[out.Arch], pkgs...)
+		}
+	}
+
+	return sboms, nil
+}
+
+// parseSPDX parses an SPDX JSON document.
+func parseSPDX(data []byte) ([]SBOMPackage, error) {
+	var doc spdxDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse SPDX SBOM: %w", err)
+	}
+
+	pkgs := make([]SBOMPackage, 0, len(doc.Packages))
+
+	for _, p := range doc.Packages {
+		if p.Name == "" {
+			continue
+		}
+
+		pkg := SBOMPackage{
+			Name:     p.Name,
+			Version:  p.VersionInfo,
+			Supplier: p.Supplier,
+			License:  spdxLicense(p.LicenseDeclared, p.LicenseConcluded),
+		}
+
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				pkg.PURL = ref.ReferenceLocator
+				break
+			}
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+// parseCycloneDX parses a CycloneDX JSON document.
+func parseCycloneDX(data []byte) ([]SBOMPackage, error) {
+	var doc cycloneDXDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse CycloneDX SBOM: %w", err)
+	}
+
+	pkgs := make([]SBOMPackage, 0, len(doc.Components))
+
+	for _, c := range doc.Components {
+		if c.Name == "" {
+			continue
+		}
+
+		pkg := SBOMPackage{
+			Name:     c.Name,
+			Version:  c.Version,
+			PURL:     c.PURL,
+			Supplier: c.Supplier.Name,
+		}
+
+		if pkg.Supplier == "" {
+			pkg.Supplier = c.Publisher
+		}
+
+		var licenses []string
+
+		for _, l := range c.Licenses {
+			switch {
+			case l.Expression != "":
+				licenses = append(licenses, l.Expression)
+			case l.License.ID != "":
+				licenses = append(licenses, l.License.ID)
+			case l.License.Name != "":
+				licenses = append(licenses, l.License.Name)
+			}
+		}
+
+		pkg.License = strings.Join(licenses, " AND ")
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+// spdxLicense returns the declared license, falling back to the concluded one.
+// SPDX placeholders ("NOASSERTION", "NONE") are treated as empty.
+func spdxLicense(declared, concluded string) string {
+	for _, l := range []string{declared, concluded} {
+		if l != "" && l != "NOASSERTION" && l != "NONE" {
+			return l
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,136 @@
+package apkox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSPDXSBOM = `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {
+      "SPDXID": "SPDXRef-Package-busybox",
+      "name": "busybox",
+      "versionInfo": "1.36.1-r1",
+      "supplier": "Organization: Wolfi",
+      "licenseDeclared": "GPL-2.0-only",
+      "licenseConcluded": "NOASSERTION",
+      "externalRefs": [
+        {"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:apk/wolfi/busybox@1.36.1-r1?arch=x86_64"}
+      ]
+    },
+    {"SPDXID": "SPDXRef-Package-glibc", "name": "glibc", "versionInfo": "2.39-r1", "licenseDeclared": "NOASSERTION", "licenseConcluded": "LGPL-2.1-or-later"},
+    {"SPDXID": "SPDXRef-Unnamed"}
+  ]
+}`
+
+const testCycloneDXSBOM = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "components": [
+    {
+      "type": "library",
+      "name": "busybox",
+      "version": "1.36.1-r1",
+      "purl": "pkg:apk/wolfi/busybox@1.36.1-r1?arch=x86_64",
+      "supplier": {"name": "Wolfi"},
+      "licenses": [{"license": {"id": "GPL-2.0-only"}}]
+    },
+    {
+      "type": "library",
+      "name": "openssl",
+      "version": "3.2.1-r0",
+      "publisher": "Wolfi",
+      "licenses": [{"expression": "Apache-2.0 OR MIT"}, {"license": {"name": "Custom"}}]
+    }
+  ]
+}`
+
+func TestParseSBOM(t *testing.T) {
+	t.Run("SPDX", func(t *testing.T) {
+		pkgs, err := ParseSBOM([]byte(testSPDXSBOM))
+		if err != nil {
+			t.Fatalf("ParseSBOM() error = %v", err)
+		}
+
+		want := []SBOMPackage{
+			{
+				Name:     "busybox",
+				Version:  "1.36.1-r1",
+				PURL:     "pkg:apk/wolfi/busybox@1.36.1-r1?arch=x86_64",
+				License:  "GPL-2.0-only",
+				Supplier: "Organization: Wolfi",
+			},
+			{Name: "glibc", Version: "2.39-r1", License: "LGPL-2.1-or-later"},
+		}
+
+		if !reflect.DeepEqual(pkgs, want) {
+			t.Errorf("ParseSBOM() = %+v, want %+v", pkgs, want)
+		}
+	})
+
+	t.Run("CycloneDX", func(t *testing.T) {
+		pkgs, err := ParseSBOM([]byte(testCycloneDXSBOM))
+		if err != nil {
+			t.Fatalf("ParseSBOM() error = %v", err)
+		}
+
+		want := []SBOMPackage{
+			{
+				Name:     "busybox",
+				Version:  "1.36.1-r1",
+				PURL:     "pkg:apk/wolfi/busybox@1.36.1-r1?arch=x86_64",
+				License:  "GPL-2.0-only",
+				Supplier: "Wolfi",
+			},
+			{Name: "openssl", Version: "3.2.1-r0", License: "Apache-2.0 OR MIT AND Custom", Supplier: "Wolfi"},
+		}
+
+		if !reflect.DeepEqual(pkgs, want) {
+			t.Errorf("ParseSBOM() = %+v, want %+v", pkgs, want)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		for _, data := range []string{`{"foo": "bar"}`, `not json`} {
+			if _, err := ParseSBOM([]byte(data)); err == nil {
+				t.Errorf("ParseSBOM(%q) expected error", data)
+			}
+		}
+	})
+}
+
+func TestLoadArchSBOMs(t *testing.T) {
+	dir := t.TempDir()
+
+	builder := NewApkoBuilder().
+		WithOutputImage("my-image").
+		WithOutputTarball(filepath.Join(dir, "image.tar")).
+		WithSBOM(true).
+		WithSBOMPath(dir).
+		WithBuildArchs(ArchX8664)
+
+	outputs, err := builder.ArchOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(outputs[0].SBOMPaths[0], []byte(testSPDXSBOM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sboms, err := LoadArchSBOMs(outputs)
+	if err != nil {
+		t.Fatalf("LoadArchSBOMs() error = %v", err)
+	}
+
+	if len(sboms[ArchX8664]) != 2 {
+		t.Errorf("LoadArchSBOMs() = %+v", sboms)
+	}
+
+	if _, err := LoadSBOM(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadSBOM() expected error for missing file")
+	}
+}
@@ -516,3 +516,204 @@ func TestApkoBuilderCommand(t *testing.T) {
 		t.Errorf("Command mismatch.\nExpected: %v\nGot: %v", expected, cmd)
 	}
 }
+
+func TestApkoBuilderBuildCommandGolden(t *testing.T) {
+	base := func() *ApkoBuilder {
+		return NewApkoBuilder().
+			WithConfigFile("config.yaml").
+			WithOutputImage("my-image").
+			WithOutputTarball("output.tar").
+			WithSBOM(true).
+			WithVCS(true)
+	}
+
+	positional := []string{"config.yaml", "my-image:latest", "output.tar"}
+
+	tests := []struct {
+		name    string
+		builder *ApkoBuilder
+		flags   []string
+	}{
+		{
+			name:    "Minimal",
+			builder: base(),
+		},
+		{
+			name:    "SBOM and VCS disabled",
+			builder: base().WithSBOM(false).WithVCS(false),
+			flags:   []string{"--sbom=false", "--vcs=false"},
+		},
+		{
+			name:    "Cache dir",
+			builder: base().WithCacheDir("/cache"),
+			flags:   []string{"--cache-dir", "/cache"},
+		},
+		{
+			name:    "Multiple keyrings",
+			builder: base().WithKeyring("/etc/apk/keys/a.pub").WithKeyring("/etc/apk/keys/b.pub"),
+			flags:   []string{"--keyring-append", "/etc/apk/keys/a.pub", "--keyring-append", "/etc/apk/keys/b.pub"},
+		},
+		{
+			name:    "Preset keyrings",
+			builder: base().WithWolfiKeyring().WithAlpineKeyring(),
+			flags: []string{
+				"--keyring-append", ApkoWolfiSigninRsaKeyPath,
+				"--keyring-append", ApkoAlpineSigninRsaKeyPath,
+			},
+		},
+		{
+			name:    "Preset keyring not duplicated",
+			builder: base().WithKeyRingWolfi().WithWolfiKeyring(),
+			flags:   []string{"--keyring-append", ApkoWolfiSigninRsaKeyPath},
+		},
+		{
+			name:    "Architecture",
+			builder: base().WithBuildArch(ArchAarch64),
+			flags:   []string{"--arch", "aarch64"},
+		},
+		{
+			name:    "Build context",
+			builder: base().WithBuildContext("/ctx"),
+			flags:   []string{"--build-repository-append", "/ctx"},
+		},
+		{
+			name:    "Debug",
+			builder: base().WithDebug(),
+			flags:   []string{"--debug"},
+		},
+		{
+			name:    "Offline",
+			builder: base().WithOffline(),
+			flags:   []string{"--offline"},
+		},
+		{
+			name:    "No network",
+			builder: base().WithNoNetwork(),
+			flags:   []string{"--no-network"},
+		},
+		{
+			name:    "Keyring append plaintext",
+			builder: base().WithKeyringAppendPlaintext("key1").WithKeyringAppendPlaintext("key2"),
+			flags:   []string{"--keyring-append-plaintext", "key1", "--keyring-append-plaintext", "key2"},
+		},
+		{
+			name:    "Repository append",
+			builder: base().WithRepositoryAppend("https://a.example.com").WithRepositoryAppend("https://b.example.com"),
+			flags: []string{
+				"--repository-append", "https://a.example.com",
+				"--repository-append", "https://b.example.com",
+			},
+		},
+		{
+			name:    "Package append",
+			builder: base().WithPackageAppend("curl", "git"),
+			flags:   []string{"--package-append", "curl", "--package-append", "git"},
+		},
+		{
+			name:    "Annotations sorted by key",
+			builder: base().WithAnnotations(map[string]string{"org.b": "2", "org.a": "1"}),
+			flags:   []string{"--annotations", "org.a:1", "--annotations", "org.b:2"},
+		},
+		{
+			name:    "Build date",
+			builder: base().WithBuildDate("2024-01-01T00:00:00Z"),
+			flags:   []string{"--build-date", "2024-01-01T00:00:00Z"},
+		},
+		{
+			name:    "Timestamp",
+			builder: base().WithTimestamp("1704067200"),
+			flags:   []string{"--timestamp", "1704067200"},
+		},
+		{
+			name:    "Lockfile",
+			builder: base().WithLockfile("apko.lock.json"),
+			flags:   []string{"--lockfile", "apko.lock.json"},
+		},
+		{
+			name:    "SBOM path and formats",
+			builder: base().WithSBOMPath("/sbom").WithSBOMFormats("spdx", "cyclonedx"),
+			flags:   []string{"--sbom-path", "/sbom", "--sbom-formats", "spdx", "--sbom-formats", "cyclonedx"},
+		},
+		{
+			name:    "Log level and policy",
+			builder: base().WithLogLevel("debug").WithLogPolicy("builtin:stderr", "/tmp/apko.log"),
+			flags:   []string{"--log-level", "debug", "--log-policy", "builtin:stderr", "--log-policy", "/tmp/apko.log"},
+		},
+		{
+			name:    "Workdir",
+			builder: base().WithWorkdir("/work"),
+			flags:   []string{"--workdir", "/work"},
+		},
+		{
+			name:    "Additional tags",
+			builder: base().WithTags("v1", "v1.2"),
+			flags:   []string{"--tag", "my-image:v1", "--tag", "my-image:v1.2"},
+		},
+		{
+			name: "All options",
+			builder: base().
+				WithCacheDir("/cache").
+				WithKeyring("/etc/apk/keys/a.pub").
+				WithBuildArch(ArchX8664).
+				WithBuildContext("/ctx").
+				WithSBOM(false).
+				WithVCS(false).
+				WithDebug().
+				WithOffline().
+				WithNoNetwork().
+				WithKeyringAppendPlaintext("key1").
+				WithRepositoryAppend("https://a.example.com").
+				WithPackageAppend("curl").
+				WithAnnotations(map[string]string{"org.a": "1"}).
+				WithBuildDate("2024-01-01T00:00:00Z").
+				WithTimestamp("1704067200").
+				WithLockfile("apko.lock.json").
+				WithSBOMPath("/sbom").
+				WithSBOMFormats("spdx").
+				WithLogLevel("info").
+				WithLogPolicy("builtin:stderr").
+				WithWorkdir("/work").
+				WithTags("v1"),
+			flags: []string{
+				"--cache-dir", "/cache",
+				"--keyring-append", "/etc/apk/keys/a.pub",
+				"--arch", "x86_64",
+				"--build-repository-append", "/ctx",
+				"--sbom=false",
+				"--vcs=false",
+				"--debug",
+				"--offline",
+				"--no-network",
+				"--keyring-append-plaintext", "key1",
+				"--repository-append", "https://a.example.com",
+				"--package-append", "curl",
+				"--annotations", "org.a:1",
+				"--build-date", "2024-01-01T00:00:00Z",
+				"--timestamp", "1704067200",
+				"--lockfile", "apko.lock.json",
+				"--sbom-path", "/sbom",
+				"--sbom-formats", "spdx",
+				"--log-level", "info",
+				"--log-policy", "builtin:stderr",
+				"--workdir", "/work",
+				"--tag", "my-image:v1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := tt.builder.BuildCommand()
+			if err != nil {
+				t.Fatalf("BuildCommand returned unexpected error: %v", err)
+			}
+
+			expected := append([]string{"apko", "build"}, tt.flags...)
+			expected = append(expected, positional...)
+
+			if !reflect.DeepEqual(cmd, expected) {
+				t.Errorf("Command mismatch.\nExpected: %v\nGot:      %v", expected, cmd)
+			}
+		})
+	}
+}
@@ -26,6 +26,11 @@
 //	}
 //	// Use cmd, e.g., fmt.Println(*cmd) // Output: [go run main.go --verbose]
 //
+// For arguments with spaces, quotes or "$", use the Command model (NewCommand), which keeps the
+// argument vector exact, and Quote, Join and Split, which render and parse POSIX shell command lines.
+// Multi-step scripts, such as installers, are built with NewScript. Secrets are kept out of command
+// lines with SecretRef placeholders, and out of logs with Redact.
+//
 // This package is intended for developers who need to programmatically
 // generate and manage command-line commands in their Go applications.
 package cmdx
@@ -39,7 +44,8 @@ import (
 )
 
 // GenerateCommand generates a command with the provided arguments.
-// It ensures that arguments with spaces are handled correctly.
+// An unquoted argument with spaces is split once, at its first space, into two arguments.
+// Use NewCommand to keep every argument exactly as given.
 //
 // Parameters:
 //   - command: A string representing the main command to be executed.
@@ -112,7 +118,8 @@ func ConvertCMDToString(cmd *types.DaggerCMD) string {
 }
 
 // GenerateShCommand generates a command wrapped for execution using `sh -c`.
-// It ensures that arguments with spaces are correctly handled.
+// Arguments are joined with spaces and are not escaped, so the shell interprets quotes,
+// `$` and operators in them. Use NewCommand(...).ShellCMD() to quote every argument.
 //
 // Parameters:
 //   - command: A string representing the main command to be executed.
@@ -177,22 +184,33 @@ func GenerateSHCommandAsDaggerCMD(command string, args ...string) (*types.Dagger
 }
 
 // GenerateDaggerCMDFromStr generates a DaggerCMD from a plain command string.
-// It splits the command string into a slice of strings, ensuring it handles
-// special characters and consecutive spaces correctly.
+// It splits the command string into its arguments following the POSIX sh quoting rules (see Split),
+// so quoted and escaped arguments are kept as single arguments and consecutive spaces are ignored.
 //
 // Parameters:
 //   - commands: A string representing the command.
 //
 // Returns:
 //   - A slice of strings representing the command and its arguments.
-//   - An error if the command string is empty or invalid.
+//   - An error if the command string is empty or has unterminated quotes.
+//
+// Example:
+//
+//	cmd, err := GenerateDaggerCMDFromStr(`echo 'Hello, World!' && echo "Done!"`)
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(cmd) // Output: [echo Hello, World! && echo Done!]
 func GenerateDaggerCMDFromStr(commands string) ([]string, error) {
 	if strings.TrimSpace(commands) == "" {
 		return nil, errors.New("command string cannot be empty")
 	}
 
-	// Split command string by spaces
-	cmdSlice := strings.Fields(commands)
+	cmdSlice, err := Split(commands)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command string: %w", err)
+	}
+
 	if len(cmdSlice) == 0 {
 		return nil, errors.New("invalid command string")
 	}
@@ -295,9 +295,21 @@ func TestGenerateDaggerCMDFromStr(t *testing.T) {
 		{
 			name:        "Command with special characters",
 			commands:    "echo 'Hello, World!' && echo 'Done!'",
-			expected:    []string{"echo", "'Hello,", "World!'", "&&", "echo", "'Done!'"},
+			expected:    []string{"echo", "Hello, World!", "&&", "echo", "Done!"},
 			expectError: false,
 		},
+		{
+			name:        "Command with double quotes and escapes",
+			commands:    `terraform plan -var "name=my app" -var tag=\"v1\"`,
+			expected:    []string{"terraform", "plan", "-var", "name=my app", "-var", `tag="v1"`},
+			expectError: false,
+		},
+		{
+			name:        "Command with unterminated quote",
+			commands:    "echo 'Hello",
+			expected:    nil,
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -2,6 +2,8 @@ package cmdx
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -16,6 +18,9 @@ import (
 //
 // Returns:
 //   - A string containing the complete curl command.
+//
+// Headers are rendered sorted by name. For other methods, request bodies, retries, TLS options
+// or credentials read from environment variables, use NewCurlRequest.
 func BuildCurlCommand(
 	baseURL string,
 	headers map[string]string,
@@ -25,18 +30,30 @@ func BuildCurlCommand(
 ) string {
 	curlCmd := fmt.Sprintf("curl -m %d", int(timeout.Seconds()))
 
-	for key, value := range headers {
-		curlCmd += fmt.Sprintf(" -H '%s: %s'", key, value)
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		curlCmd += fmt.Sprintf(" -H '%s: %s'", escapeSingleQuoted(key), escapeSingleQuoted(headers[key]))
 	}
 
 	switch authType {
 	case "basic":
-		curlCmd += fmt.Sprintf(" -u '%s'", authCredentials)
+		curlCmd += fmt.Sprintf(" -u '%s'", escapeSingleQuoted(authCredentials))
 	case "bearer":
-		curlCmd += fmt.Sprintf(" -H 'Authorization: Bearer %s'", authCredentials)
+		curlCmd += fmt.Sprintf(" -H 'Authorization: Bearer %s'", escapeSingleQuoted(authCredentials))
 	}
 
-	curlCmd += fmt.Sprintf(" '%s'", baseURL)
+	curlCmd += fmt.Sprintf(" '%s'", escapeSingleQuoted(baseURL))
 
 	return curlCmd
 }
+
+// escapeSingleQuoted escapes the single quotes of a string placed inside single quotes.
+func escapeSingleQuoted(s string) string {
+	return strings.ReplaceAll(s, "'", `'"'"'`)
+}
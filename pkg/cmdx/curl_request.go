@@ -0,0 +1,498 @@
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// headerNameRegex matches valid HTTP header names (RFC 9110 tokens).
+var headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// methodRegex matches valid HTTP methods.
+var methodRegex = regexp.MustCompile(`^[A-Z]+$`)
+
+// curlHeader is a request header. Its value may embed environment variable references (see envRef).
+type curlHeader struct {
+	name  string
+	value string
+}
+
+// curlQueryParam is a query parameter appended to the request URL.
+type curlQueryParam struct {
+	key   string
+	value string
+}
+
+// CurlRequest builds a curl command line. Every argument is kept exactly (see Command) and quoted only
+// when rendered for a shell, and headers are rendered in the order they were added, so the output is deterministic.
+//
+// Credentials can be read from environment variables (e.g., WithBearerTokenFromEnv), so that their
+// values never appear in the command line: the request is then rendered as a "sh -c" script that
+// expands them at runtime. Literal values cannot embed secret placeholders (see SecretRef); read secrets
+// with the FromEnv options instead.
+//
+// Invalid options do not stop the chain; their errors are reported by Render, Argv and DaggerCMD.
+type CurlRequest struct {
+	url            string
+	method         string
+	query          []curlQueryParam
+	headers        []curlHeader
+	basicAuth      string
+	data           string
+	hasData        bool
+	dataFile       string
+	outputFile     string
+	fail           bool
+	silent         bool
+	followRedirect bool
+	timeout        time.Duration
+	retries        int
+	retryDelay     time.Duration
+	caCert         string
+	insecure       bool
+	proxy          string
+	errs           []error
+}
+
+// NewCurlRequest creates a CurlRequest for the given http or https URL.
+//
+// Example:
+//
+//	cmd, err := NewCurlRequest("https://api.example.com/v1/items").
+//	    WithMethod("POST").
+//	    WithHeader("Content-Type", "application/json").
+//	    WithBearerTokenFromEnv("API_TOKEN").
+//	    WithBody(`{"name":"item"}`).
+//	    WithFail().
+//	    WithRetry(3, 2*time.Second).
+//	    DaggerCMD()
+//	if err != nil {
+//	    // handle error
+//	}
+//	// cmd: [sh -c curl -X POST --fail --retry 3 --retry-delay 2 -H 'Content-Type: application/json'
+//	//      -H "Authorization: Bearer ${API_TOKEN}" --data-raw '{"name":"item"}' https://api.example.com/v1/items]
+func NewCurlRequest(rawURL string) *CurlRequest {
+	r := &CurlRequest{url: rawURL}
+
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil:
+		r.errs = append(r.errs, fmt.Errorf("invalid URL %q: %w", rawURL, err))
+	case (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		r.errs = append(r.errs, fmt.Errorf("invalid URL %q: an http or https URL with a host is required", rawURL))
+	}
+
+	return r
+}
+
+// WithMethod sets the HTTP method (e.g., "POST"). By default, curl uses GET, or POST when a body is set.
+func (r *CurlRequest) WithMethod(method string) *CurlRequest {
+	method = strings.ToUpper(method)
+	if !methodRegex.MatchString(method) {
+		r.errs = append(r.errs, fmt.Errorf("invalid HTTP method: %q", method))
+		return r
+	}
+
+	r.method = method
+
+	return r
+}
+
+// WithQueryParam appends a query parameter to the URL. Parameters are encoded and kept in the order they were added.
+func (r *CurlRequest) WithQueryParam(key, value string) *CurlRequest {
+	if !r.validLiteral("query parameter", key, value) {
+		return r
+	}
+
+	r.query = append(r.query, curlQueryParam{key: key, value: value})
+
+	return r
+}
+
+// WithHeader sets a header. Setting a header that already exists, compared case-insensitively,
+// replaces its value in place; new headers are rendered in the order they were added.
+func (r *CurlRequest) WithHeader(name, value string) *CurlRequest {
+	if err := validateHeader(name, value); err != nil {
+		r.errs = append(r.errs, err)
+		return r
+	}
+
+	return r.setHeader(name, value)
+}
+
+// WithHeaderFromEnv sets a header whose value is read from an environment variable at runtime.
+func (r *CurlRequest) WithHeaderFromEnv(name, envVar string) *CurlRequest {
+	if err := validateHeader(name, ""); err != nil {
+		r.errs = append(r.errs, err)
+		return r
+	}
+
+	if !r.validEnvName(envVar) {
+		return r
+	}
+
+	return r.setHeader(name, envRef(envVar))
+}
+
+// WithBearerToken sets the "Authorization: Bearer <token>" header.
+// The token appears in the command line; prefer WithBearerTokenFromEnv.
+func (r *CurlRequest) WithBearerToken(token string) *CurlRequest {
+	return r.WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithBearerTokenFromEnv sets the "Authorization: Bearer <token>" header, with the token read
+// from an environment variable at runtime.
+func (r *CurlRequest) WithBearerTokenFromEnv(envVar string) *CurlRequest {
+	if !r.validEnvName(envVar) {
+		return r
+	}
+
+	return r.setHeader("Authorization", "Bearer "+envRef(envVar))
+}
+
+// WithBasicAuth sets the basic authentication credentials (curl -u).
+// The password appears in the command line; prefer WithBasicAuthFromEnv.
+func (r *CurlRequest) WithBasicAuth(username, password string) *CurlRequest {
+	if !r.validLiteral("basic authentication credentials", username, password) {
+		return r
+	}
+
+	r.basicAuth = username + ":" + password
+
+	return r
+}
+
+// WithBasicAuthFromEnv sets the basic authentication credentials (curl -u), with the username
+// and password read from environment variables at runtime.
+func (r *CurlRequest) WithBasicAuthFromEnv(usernameEnvVar, passwordEnvVar string) *CurlRequest {
+	if !r.validEnvName(usernameEnvVar) || !r.validEnvName(passwordEnvVar) {
+		return r
+	}
+
+	r.basicAuth = envRef(usernameEnvVar) + ":" + envRef(passwordEnvVar)
+
+	return r
+}
+
+// WithBody sets the request body, sent as is (curl --data-raw). It replaces any data file.
+func (r *CurlRequest) WithBody(body string) *CurlRequest {
+	if !r.validLiteral("body", body) {
+		return r
+	}
+
+	r.data, r.hasData, r.dataFile = body, true, ""
+
+	return r
+}
+
+// WithDataFile sets a file whose content is sent as the request body, as is (curl --data-binary @file).
+// It replaces any body.
+func (r *CurlRequest) WithDataFile(path string) *CurlRequest {
+	if !r.validLiteral("data file", path) {
+		return r
+	}
+
+	r.dataFile, r.data, r.hasData = path, "", false
+
+	return r
+}
+
+// WithOutputFile writes the response body to a file (curl -o).
+func (r *CurlRequest) WithOutputFile(path string) *CurlRequest {
+	if !r.validLiteral("output file", path) {
+		return r
+	}
+
+	r.outputFile = path
+
+	return r
+}
+
+// WithFail makes curl fail on HTTP errors (status 400 and above) instead of printing the error page (curl --fail).
+func (r *CurlRequest) WithFail() *CurlRequest {
+	r.fail = true
+	return r
+}
+
+// WithSilent hides the progress meter, but still shows errors (curl -sS).
+func (r *CurlRequest) WithSilent() *CurlRequest {
+	r.silent = true
+	return r
+}
+
+// WithFollowRedirects follows redirects (curl -L).
+func (r *CurlRequest) WithFollowRedirects() *CurlRequest {
+	r.followRedirect = true
+	return r
+}
+
+// WithTimeout sets the maximum time the whole request may take (curl --max-time).
+func (r *CurlRequest) WithTimeout(timeout time.Duration) *CurlRequest {
+	r.timeout = timeout
+	return r
+}
+
+// WithRetry retries transient failures up to retries times (curl --retry), waiting delay between
+// attempts (curl --retry-delay, rounded to seconds). A zero delay keeps the curl exponential backoff.
+func (r *CurlRequest) WithRetry(retries int, delay time.Duration) *CurlRequest {
+	if retries < 0 {
+		r.errs = append(r.errs, fmt.Errorf("invalid number of retries: %d", retries))
+		return r
+	}
+
+	r.retries, r.retryDelay = retries, delay
+
+	return r
+}
+
+// WithCACert verifies the server certificate against the CA certificates of a file (curl --cacert).
+func (r *CurlRequest) WithCACert(path string) *CurlRequest {
+	if !r.validLiteral("CA certificate file", path) {
+		return r
+	}
+
+	r.caCert = path
+
+	return r
+}
+
+// WithInsecure skips the verification of the server certificate (curl --insecure).
+func (r *CurlRequest) WithInsecure() *CurlRequest {
+	r.insecure = true
+	return r
+}
+
+// WithProxy sends the request through a proxy (curl --proxy), e.g. "http://proxy.internal:3128".
+func (r *CurlRequest) WithProxy(proxy string) *CurlRequest {
+	if !r.validLiteral("proxy", proxy) {
+		return r
+	}
+
+	r.proxy = proxy
+
+	return r
+}
+
+// Argv returns the argument vector of the curl command, to be executed without a shell.
+// It returns an error if credentials are read from environment variables, since they need
+// a shell to be expanded: use DaggerCMD or Render instead.
+func (r *CurlRequest) Argv() ([]string, error) {
+	argv, err := r.argv()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := envRefs(argv...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid curl request: %w", err)
+	}
+
+	if len(refs) > 0 {
+		return nil, fmt.Errorf("the request reads environment variables %s and must run in a shell",
+			strings.Join(refs, ", "))
+	}
+
+	return argv, nil
+}
+
+// Render returns the curl command as a POSIX shell command line, with its arguments quoted and
+// the credentials read from environment variables rendered as "${NAME}" expansions.
+func (r *CurlRequest) Render() (string, error) {
+	argv, err := r.argv()
+	if err != nil {
+		return "", err
+	}
+
+	line, err := joinWords(argv...)
+	if err != nil {
+		return "", fmt.Errorf("invalid curl request: %w", err)
+	}
+
+	return line, nil
+}
+
+// DaggerCMD returns the curl command as a DaggerCMD: its argument vector, or a "sh -c" script
+// when credentials are read from environment variables.
+func (r *CurlRequest) DaggerCMD() (types.DaggerCMD, error) {
+	argv, err := r.argv()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := envRefs(argv...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid curl request: %w", err)
+	}
+
+	if len(refs) == 0 {
+		return types.DaggerCMD(argv), nil
+	}
+
+	line, err := joinWords(argv...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid curl request: %w", err)
+	}
+
+	return types.DaggerCMD{"sh", "-c", line}, nil
+}
+
+// EnvVars returns the names of the environment variables the request reads, in order of first use.
+func (r *CurlRequest) EnvVars() []string {
+	argv, err := r.argv()
+	if err != nil {
+		return nil
+	}
+
+	refs, err := envRefs(argv...)
+	if err != nil {
+		return nil
+	}
+
+	return refs
+}
+
+// argv builds the argument vector, with the environment variable references embedded.
+func (r *CurlRequest) argv() ([]string, error) {
+	if err := errors.Join(r.errs...); err != nil {
+		return nil, fmt.Errorf("invalid curl request: %w", err)
+	}
+
+	argv := []string{"curl"}
+
+	if implied := r.impliedMethod(); r.method != "" && r.method != implied {
+		argv = append(argv, "-X", r.method)
+	}
+
+	argv = appendIf(argv, r.fail, "--fail")
+	argv = appendIf(argv, r.silent, "-sS")
+	argv = appendIf(argv, r.followRedirect, "-L")
+
+	if r.timeout > 0 {
+		argv = append(argv, "--max-time", strconv.FormatFloat(r.timeout.Seconds(), 'f', -1, 64))
+	}
+
+	if r.retries > 0 {
+		argv = append(argv, "--retry", strconv.Itoa(r.retries))
+		if delay := int(r.retryDelay / time.Second); delay > 0 {
+			argv = append(argv, "--retry-delay", strconv.Itoa(delay))
+		}
+	}
+
+	argv = appendIf(argv, r.caCert != "", "--cacert", r.caCert)
+	argv = appendIf(argv, r.insecure, "--insecure")
+	argv = appendIf(argv, r.proxy != "", "--proxy", r.proxy)
+	argv = appendIf(argv, r.basicAuth != "", "-u", r.basicAuth)
+
+	for _, h := range r.headers {
+		argv = append(argv, "-H", h.name+": "+h.value)
+	}
+
+	argv = appendIf(argv, r.hasData, "--data-raw", r.data)
+	argv = appendIf(argv, r.dataFile != "", "--data-binary", "@"+r.dataFile)
+	argv = appendIf(argv, r.outputFile != "", "-o", r.outputFile)
+
+	return append(argv, r.fullURL()), nil
+}
+
+// impliedMethod returns the method curl uses without -X.
+func (r *CurlRequest) impliedMethod() string {
+	if r.hasData || r.dataFile != "" {
+		return "POST"
+	}
+
+	return "GET"
+}
+
+// fullURL returns the URL with the query parameters appended.
+func (r *CurlRequest) fullURL() string {
+	if len(r.query) == 0 {
+		return r.url
+	}
+
+	params := make([]string, len(r.query))
+	for i, p := range r.query {
+		params[i] = url.QueryEscape(p.key) + "=" + url.QueryEscape(p.value)
+	}
+
+	base, fragment, hasFragment := strings.Cut(r.url, "#")
+
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+
+	full := base + separator + strings.Join(params, "&")
+	if hasFragment {
+		full += "#" + fragment
+	}
+
+	return full
+}
+
+// setHeader sets a header, replacing an existing one with the same name in place.
+func (r *CurlRequest) setHeader(name, value string) *CurlRequest {
+	for i := range r.headers {
+		if strings.EqualFold(r.headers[i].name, name) {
+			r.headers[i].value = value
+			return r
+		}
+	}
+
+	r.headers = append(r.headers, curlHeader{name: name, value: value})
+
+	return r
+}
+
+// validEnvName reports whether the environment variable name is valid, recording an error if not.
+func (r *CurlRequest) validEnvName(name string) bool {
+	if !envNameRegex.MatchString(name) {
+		r.errs = append(r.errs, fmt.Errorf("invalid environment variable name: %q", name))
+		return false
+	}
+
+	return true
+}
+
+// validLiteral reports whether literal values are free of NUL characters, recording an error if not.
+// NUL characters delimit the environment variable references (see envRef), so literal values must not
+// contain them, or they could be rendered as shell expansions.
+func (r *CurlRequest) validLiteral(what string, values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(value, envRefMarker) {
+			r.errs = append(r.errs, fmt.Errorf("invalid %s: NUL characters are not allowed", what))
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateHeader checks that the header name is a valid token and that the value has no line breaks
+// or NUL characters.
+func validateHeader(name, value string) error {
+	if !headerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid header name: %q", name)
+	}
+
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("invalid value for header %s: line breaks and NUL characters are not allowed", name)
+	}
+
+	return nil
+}
+
+// appendIf appends the arguments if the condition holds.
+func appendIf(argv []string, condition bool, args ...string) []string {
+	if condition {
+		return append(argv, args...)
+	}
+
+	return argv
+}
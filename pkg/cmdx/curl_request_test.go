@@ -0,0 +1,182 @@
+package cmdx
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestCurlRequestArgv(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *CurlRequest
+		expected []string
+	}{
+		{
+			name:     "Plain GET",
+			request:  NewCurlRequest("https://api.example.com"),
+			expected: []string{"curl", "https://api.example.com"},
+		},
+		{
+			name: "All options",
+			request: NewCurlRequest("https://api.example.com/items?page=1").
+				WithMethod("put").
+				WithFail().
+				WithSilent().
+				WithFollowRedirects().
+				WithTimeout(1500*time.Millisecond).
+				WithRetry(3, 2*time.Second).
+				WithCACert("/etc/ssl/ca.pem").
+				WithInsecure().
+				WithProxy("http://proxy.internal:3128").
+				WithBasicAuth("user", "p'ss").
+				WithHeader("X-B", "2").
+				WithHeader("X-A", "1").
+				WithHeader("x-b", "3").
+				WithQueryParam("q", "a b&c").
+				WithBody(`{"a":1}`).
+				WithOutputFile("/tmp/out.json"),
+			expected: []string{
+				"curl", "-X", "PUT", "--fail", "-sS", "-L", "--max-time", "1.5", "--retry", "3", "--retry-delay", "2",
+				"--cacert", "/etc/ssl/ca.pem", "--insecure", "--proxy", "http://proxy.internal:3128", "-u", "user:p'ss",
+				"-H", "X-B: 3", "-H", "X-A: 1", "--data-raw", `{"a":1}`, "-o", "/tmp/out.json",
+				"https://api.example.com/items?page=1&q=a+b%26c",
+			},
+		},
+		{
+			name:     "Implied POST with data file",
+			request:  NewCurlRequest("http://localhost:8080/upload").WithMethod("POST").WithDataFile("/tmp/body.bin"),
+			expected: []string{"curl", "--data-binary", "@/tmp/body.bin", "http://localhost:8080/upload"},
+		},
+		{
+			name:     "Query params before fragment",
+			request:  NewCurlRequest("https://example.com/docs#top").WithQueryParam("v", "1"),
+			expected: []string{"curl", "https://example.com/docs?v=1#top"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv, err := tt.request.Argv()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, argv)
+
+			cmd, err := tt.request.DaggerCMD()
+			require.NoError(t, err)
+			assert.Equal(t, types.DaggerCMD(tt.expected), cmd)
+
+			line, err := tt.request.Render()
+			require.NoError(t, err)
+
+			split, err := Split(line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, split)
+		})
+	}
+}
+
+func TestCurlRequestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *CurlRequest
+		errText string
+	}{
+		{"Relative URL", NewCurlRequest("/items"), "http or https URL"},
+		{"Unsupported scheme", NewCurlRequest("ftp://example.com"), "http or https URL"},
+		{"Invalid method", NewCurlRequest("https://example.com").WithMethod("GE T"), "invalid HTTP method"},
+		{"Invalid header name", NewCurlRequest("https://example.com").WithHeader("Bad Header", "x"), "invalid header name"},
+		{"Header injection", NewCurlRequest("https://example.com").WithHeader("X-A", "a\r\nX-B: b"), "line breaks"},
+		{"Header expansion injection", NewCurlRequest("https://example.com").WithHeader("X", "\x00A:-`id`\x00"), "NUL"},
+		{"Invalid env name", NewCurlRequest("https://example.com").WithBearerTokenFromEnv("MY-TOKEN"), "MY-TOKEN"},
+		{"Negative retries", NewCurlRequest("https://example.com").WithRetry(-1, 0), "retries"},
+		{"URL with NUL", NewCurlRequest("https://example.com/\x00X:-$(id)\x00"), "invalid URL"},
+		{"Body injection", NewCurlRequest("https://example.com").WithBody("\x00X:-$(id)\x00"), "invalid body"},
+		{"Basic auth injection", NewCurlRequest("https://example.com").WithBasicAuth("me", "\x00X:-$(id)\x00"), "basic auth"},
+		{"Proxy injection", NewCurlRequest("https://example.com").WithProxy("\x00X:-$(id)\x00"), "invalid proxy"},
+		{"Output file injection", NewCurlRequest("https://example.com").WithOutputFile("\x00X:-$(id)\x00"), "output file"},
+		{"Data file injection", NewCurlRequest("https://example.com").WithDataFile("\x00X:-$(id)\x00"), "data file"},
+		{"CA cert injection", NewCurlRequest("https://example.com").WithCACert("\x00X:-$(id)\x00"), "CA certificate"},
+		{"Query injection", NewCurlRequest("https://example.com").WithQueryParam("q", "\x00X:-$(id)\x00"), "query parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.request.DaggerCMD()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errText)
+
+			_, err = tt.request.Render()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCurlRequestCredentialsFromEnv(t *testing.T) {
+	request := NewCurlRequest("https://api.example.com").
+		WithBearerTokenFromEnv("API_TOKEN").
+		WithHeaderFromEnv("X-Tenant", "TENANT").
+		WithBasicAuthFromEnv("API_USER", "API_TOKEN")
+
+	_, err := request.Argv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API_USER, API_TOKEN, TENANT")
+	assert.Equal(t, []string{"API_USER", "API_TOKEN", "TENANT"}, request.EnvVars())
+
+	line, err := request.Render()
+	require.NoError(t, err)
+	assert.Equal(t,
+		`curl -u "${API_USER}:${API_TOKEN}" -H "Authorization: Bearer ${API_TOKEN}" -H "X-Tenant: ${TENANT}" https://api.example.com`,
+		line)
+
+	cmd, err := request.DaggerCMD()
+	require.NoError(t, err)
+	assert.Equal(t, types.DaggerCMD{"sh", "-c", line}, cmd)
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// The values are expanded at runtime, as single arguments, without further interpretation.
+	shell := exec.Command("sh", "-c", "printf '%s\\n' "+strings.TrimPrefix(line, "curl "))
+	shell.Env = []string{"API_USER=me", `API_TOKEN=t0k "$x" 'y'`, "TENANT=a b"}
+
+	output, err := shell.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Equal(t, []string{
+		"-u", `me:t0k "$x" 'y'`,
+		"-H", `Authorization: Bearer t0k "$x" 'y'`,
+		"-H", "X-Tenant: a b",
+		"https://api.example.com",
+	}, strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"))
+}
+
+func TestQuoteWord(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"a b", "'a b'"},
+		{"Bearer " + envRef("TOKEN"), `"Bearer ${TOKEN}"`},
+		{`$x "y" \z ` + "`w` " + envRef("A") + envRef("B"), `"\$x \"y\" \\z ` + "\\`w\\` " + `${A}${B}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			word, err := quoteWord(tt.arg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, word)
+		})
+	}
+
+	for _, arg := range []string{"x\x00X:-$(id)\x00y", "\x00A:-`id`\x00", "\x00A", "\x00\x00"} {
+		_, err := quoteWord(arg)
+		require.Error(t, err, "%q", arg)
+	}
+}
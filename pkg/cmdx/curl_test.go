@@ -53,6 +53,14 @@ func TestBuildCurlCommand(t *testing.T) {
 			authCredentials: "token123",
 			want:            []string{"curl -m 90 -H 'Authorization: Bearer token123' 'https://api.example.com'"},
 		},
+		{
+			name:            "With quotes in credentials",
+			baseURL:         "https://api.example.com",
+			timeout:         30 * time.Second,
+			authType:        "basic",
+			authCredentials: "user:it's",
+			want:            []string{`curl -m 30 -u 'user:it'"'"'s' 'https://api.example.com'`},
+		},
 		{
 			name:    "With all options",
 			baseURL: "https://api.example.com",
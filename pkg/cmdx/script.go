@@ -0,0 +1,294 @@
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+const (
+	// scriptShebang is the first line of the script files written by Script.WriteScript.
+	scriptShebang = "#!/bin/sh"
+	// scriptIndent is the indentation of the steps nested in conditionals and retry loops.
+	scriptIndent = "  "
+	// heredocDelimiter is the heredoc delimiter of Script.WriteFile, suffixed if the content contains it.
+	heredocDelimiter = "DAGGERX_EOF"
+)
+
+// envNameRegex matches valid shell variable names.
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scriptLine is a rendered line of a script.
+type scriptLine struct {
+	text string
+	// verbatim lines, such as heredoc bodies, are never indented.
+	verbatim bool
+}
+
+// Script builds a POSIX shell script out of steps, quoting every argument, so that
+// multi-step pipelines such as installers are expressed without raw format strings.
+//
+// Invalid steps (e.g., an invalid variable name) do not stop the chain; their errors
+// are reported by Render, DaggerCMD and WriteScript.
+type Script struct {
+	setOptions string
+	lines      []scriptLine
+	cleanup    []string
+	retries    int
+	errs       []error
+}
+
+// NewScript creates an empty Script.
+//
+// Example:
+//
+//	cmd, err := NewScript().
+//	    WithSetOptions("-ex").
+//	    CleanupOnExit("/tmp/terraform.zip").
+//	    Retry(3, 2*time.Second, NewCommand("curl", "-fsSL", url, "-o", "/tmp/terraform.zip")).
+//	    Run("unzip", "/tmp/terraform.zip", "-d", "/usr/local/bin").
+//	    DaggerCMD()
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr = ctr.WithExec(cmd)
+func NewScript() *Script {
+	return &Script{}
+}
+
+// WithSetOptions sets the shell options enabled at the start of the script (e.g., "-ex" renders "set -ex").
+func (s *Script) WithSetOptions(options string) *Script {
+	s.setOptions = options
+	return s
+}
+
+// Run adds a step running the command with its arguments, each one quoted.
+func (s *Script) Run(name string, args ...string) *Script {
+	return s.RunCommand(NewCommand(name, args...))
+}
+
+// RunCommand adds a step running the command. Its secret placeholders (see SecretRef) are rendered
+// as "${NAME}" expansions.
+func (s *Script) RunCommand(cmd *Command) *Script {
+	line, err := joinWords(cmd.argv...)
+	if err != nil {
+		s.errs = append(s.errs, err)
+		return s
+	}
+
+	return s.add(line)
+}
+
+// Raw adds a step written in shell syntax, added verbatim, e.g. for pipes or redirections.
+// Dynamic values must be quoted with Quote.
+func (s *Script) Raw(line string) *Script {
+	s.lines = append(s.lines, scriptLine{text: line, verbatim: true})
+	return s
+}
+
+// Export adds a step exporting an environment variable with a literal value, which may embed
+// secret placeholders (see SecretRef).
+func (s *Script) Export(name, value string) *Script {
+	if !envNameRegex.MatchString(name) {
+		s.errs = append(s.errs, fmt.Errorf("invalid environment variable name: %q", name))
+		return s
+	}
+
+	word, err := quoteWord(value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("invalid value for environment variable %s: %w", name, err))
+		return s
+	}
+
+	return s.add(fmt.Sprintf("export %s=%s", name, word))
+}
+
+// ExportExpanded adds a step exporting an environment variable whose value expands
+// other variables (e.g., "$PATH:/opt/bin"). Quotes, backslashes and backticks in the value are escaped,
+// so only "$" expansions are evaluated.
+func (s *Script) ExportExpanded(name, value string) *Script {
+	if !envNameRegex.MatchString(name) {
+		s.errs = append(s.errs, fmt.Errorf("invalid environment variable name: %q", name))
+		return s
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(value)
+
+	return s.add(fmt.Sprintf(`export %s="%s"`, name, escaped))
+}
+
+// Cd adds a step changing the working directory.
+func (s *Script) Cd(dir string) *Script {
+	return s.add(Join("cd", dir))
+}
+
+// If adds the steps added by then, run only if the condition succeeds.
+// The condition is written in shell syntax (e.g., "[ ! -f /usr/local/bin/terraform ]").
+func (s *Script) If(condition string, then func(*Script)) *Script {
+	return s.IfElse(condition, then, nil)
+}
+
+// IfElse adds the steps added by then, run if the condition succeeds, and otherwise the
+// steps added by otherwise. A nil otherwise omits the else branch.
+func (s *Script) IfElse(condition string, then, otherwise func(*Script)) *Script {
+	s.add(fmt.Sprintf("if %s; then", condition))
+	s.nest(then)
+
+	if otherwise != nil {
+		s.add("else")
+		s.nest(otherwise)
+	}
+
+	return s.add("fi")
+}
+
+// Retry adds a step running the command up to attempts times, sleeping between attempts
+// with an exponential backoff starting at the given delay (rounded to seconds, at least one).
+// The script exits with an error once every attempt failed.
+func (s *Script) Retry(attempts int, backoff time.Duration, cmd *Command) *Script {
+	line, err := joinWords(cmd.argv...)
+	if err != nil {
+		s.errs = append(s.errs, err)
+		return s
+	}
+
+	if attempts < 1 {
+		s.errs = append(s.errs, fmt.Errorf("retry of %q needs at least one attempt, got %d", line, attempts))
+		return s
+	}
+
+	delay := int(backoff / time.Second)
+	if delay < 1 {
+		delay = 1
+	}
+
+	s.retries++
+	attempt := fmt.Sprintf("_daggerx_attempt_%d", s.retries)
+	wait := fmt.Sprintf("_daggerx_delay_%d", s.retries)
+
+	s.add(fmt.Sprintf("%s=1", attempt))
+	s.add(fmt.Sprintf("%s=%d", wait, delay))
+	s.add(fmt.Sprintf("until %s; do", line))
+	s.nest(func(body *Script) {
+		body.If(fmt.Sprintf(`[ "$%s" -ge %d ]`, attempt, attempts), func(fail *Script) {
+			fail.add(Join("echo", fmt.Sprintf("command failed after %d attempts: %s", attempts, line)) + " >&2")
+			fail.add("exit 1")
+		})
+		body.add(fmt.Sprintf(`sleep "$%s"`, wait))
+		body.add(fmt.Sprintf("%[1]s=$((%[1]s + 1))", attempt))
+		body.add(fmt.Sprintf("%[1]s=$((%[1]s * 2))", wait))
+	})
+
+	return s.add("done")
+}
+
+// CleanupOnExit registers temporary files or directories that are removed when the script exits,
+// whether it succeeds or fails, through a trap set at the start of the script.
+func (s *Script) CleanupOnExit(paths ...string) *Script {
+	s.cleanup = append(s.cleanup, paths...)
+	return s
+}
+
+// WriteFile adds a step writing the content to a file through a quoted heredoc, so the content
+// is written as is, without expansion. A trailing newline is added if the content has none.
+func (s *Script) WriteFile(path, content string) *Script {
+	delimiter := heredocDelimiter
+	for i := 1; containsLine(content, delimiter); i++ {
+		delimiter = fmt.Sprintf("%s_%d", heredocDelimiter, i)
+	}
+
+	s.add(fmt.Sprintf("cat > %s <<'%s'", Quote(path), delimiter))
+	s.lines = append(s.lines,
+		scriptLine{text: strings.TrimSuffix(content, "\n"), verbatim: true},
+		scriptLine{text: delimiter, verbatim: true},
+	)
+
+	return s
+}
+
+// Render returns the script, or the errors of the invalid steps.
+func (s *Script) Render() (string, error) {
+	if err := errors.Join(s.errs...); err != nil {
+		return "", fmt.Errorf("invalid script: %w", err)
+	}
+
+	var lines []string
+	if s.setOptions != "" {
+		lines = append(lines, "set "+s.setOptions)
+	}
+
+	if len(s.cleanup) > 0 {
+		lines = append(lines, fmt.Sprintf("trap %s EXIT", Quote(Join(append([]string{"rm", "-rf", "--"}, s.cleanup...)...))))
+	}
+
+	for _, l := range s.lines {
+		lines = append(lines, l.text)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// DaggerCMD returns the script wrapped for execution by "sh -c".
+func (s *Script) DaggerCMD() (types.DaggerCMD, error) {
+	script, err := s.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	return types.DaggerCMD{"sh", "-c", script}, nil
+}
+
+// WriteScript writes the script, with a "#!/bin/sh" shebang, to an executable file at the given path.
+func (s *Script) WriteScript(path string) error {
+	script, err := s.Render()
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // The script must be executable.
+	if err := os.WriteFile(path, []byte(scriptShebang+"\n"+script+"\n"), 0o755); err != nil {
+		return fmt.Errorf("failed to write script %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// add adds a step line.
+func (s *Script) add(line string) *Script {
+	s.lines = append(s.lines, scriptLine{text: line})
+	return s
+}
+
+// nest adds the steps added by fn, indented, merging their cleanup paths and errors.
+func (s *Script) nest(fn func(*Script)) {
+	child := &Script{retries: s.retries}
+	fn(child)
+
+	for _, l := range child.lines {
+		if !l.verbatim {
+			l.text = scriptIndent + l.text
+		}
+
+		s.lines = append(s.lines, l)
+	}
+
+	s.cleanup = append(s.cleanup, child.cleanup...)
+	s.errs = append(s.errs, child.errs...)
+	s.retries = child.retries
+}
+
+// containsLine reports whether one of the lines of content is exactly line.
+func containsLine(content, line string) bool {
+	for _, l := range strings.Split(content, "\n") {
+		if l == line {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,217 @@
+package cmdx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestScriptRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   *Script
+		expected string
+	}{
+		{
+			name:     "Empty",
+			script:   NewScript(),
+			expected: "",
+		},
+		{
+			// Same script as installerx.GetTerraformInstallCommand("1.0.0", "linux", "amd64").
+			name: "Installer steps",
+			script: NewScript().
+				WithSetOptions("-ex").
+				Run("curl", "-L", "https://releases.hashicorp.com/terraform/1.0.0/terraform_1.0.0_linux_amd64.zip",
+					"-o", "/tmp/terraform.zip").
+				Run("unzip", "/tmp/terraform.zip", "-d", "/tmp").
+				Run("mv", "/tmp/terraform", "/usr/local/bin/terraform").
+				Run("chmod", "+x", "/usr/local/bin/terraform").
+				Run("rm", "/tmp/terraform.zip"),
+			expected: `set -ex
+curl -L https://releases.hashicorp.com/terraform/1.0.0/terraform_1.0.0_linux_amd64.zip -o /tmp/terraform.zip
+unzip /tmp/terraform.zip -d /tmp
+mv /tmp/terraform /usr/local/bin/terraform
+chmod +x /usr/local/bin/terraform
+rm /tmp/terraform.zip`,
+		},
+		{
+			name: "Quoted arguments, env and cd",
+			script: NewScript().
+				Export("GREETING", "it's $HOME").
+				ExportExpanded("PATH", `$PATH:/opt/"my bin"`).
+				Cd("/work dir").
+				Run("echo", "a  b").
+				Raw("ls | wc -l"),
+			expected: `export GREETING='it'"'"'s $HOME'
+export PATH="$PATH:/opt/\"my bin\""
+cd '/work dir'
+echo 'a  b'
+ls | wc -l`,
+		},
+		{
+			name: "Conditionals",
+			script: NewScript().
+				If("[ ! -d /opt ]", func(s *Script) {
+					s.Run("mkdir", "-p", "/opt")
+				}).
+				IfElse("command -v apk", func(s *Script) {
+					s.Run("apk", "add", "curl")
+				}, func(s *Script) {
+					s.Run("apt-get", "install", "-y", "curl")
+				}),
+			expected: `if [ ! -d /opt ]; then
+  mkdir -p /opt
+fi
+if command -v apk; then
+  apk add curl
+else
+  apt-get install -y curl
+fi`,
+		},
+		{
+			name: "Retry with backoff",
+			script: NewScript().
+				Retry(3, 2*time.Second, NewCommand("curl", "-fsSL", "https://example.com")),
+			expected: `_daggerx_attempt_1=1
+_daggerx_delay_1=2
+until curl -fsSL https://example.com; do
+  if [ "$_daggerx_attempt_1" -ge 3 ]; then
+    echo 'command failed after 3 attempts: curl -fsSL https://example.com' >&2
+    exit 1
+  fi
+  sleep "$_daggerx_delay_1"
+  _daggerx_attempt_1=$((_daggerx_attempt_1 + 1))
+  _daggerx_delay_1=$((_daggerx_delay_1 * 2))
+done`,
+		},
+		{
+			name: "Cleanup trap",
+			script: NewScript().
+				WithSetOptions("-e").
+				Run("touch", "/tmp/a").
+				CleanupOnExit("/tmp/a", "/tmp/my dir"),
+			expected: `set -e
+trap 'rm -rf -- /tmp/a '"'"'/tmp/my dir'"'"'' EXIT
+touch /tmp/a`,
+		},
+		{
+			name: "Heredoc not indented in conditionals",
+			script: NewScript().
+				If("true", func(s *Script) {
+					s.WriteFile("/etc/app.conf", "key=$value\n  indented\n")
+				}),
+			expected: `if true; then
+  cat > /etc/app.conf <<'DAGGERX_EOF'
+key=$value
+  indented
+DAGGERX_EOF
+fi`,
+		},
+		{
+			name:   "Heredoc delimiter in content",
+			script: NewScript().WriteFile("/tmp/f", "DAGGERX_EOF"),
+			expected: `cat > /tmp/f <<'DAGGERX_EOF_1'
+DAGGERX_EOF
+DAGGERX_EOF_1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := tt.script.Render()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, script)
+		})
+	}
+}
+
+func TestScriptRenderErrors(t *testing.T) {
+	_, err := NewScript().
+		Export("1INVALID", "x").
+		If("true", func(s *Script) {
+			s.ExportExpanded("also-invalid", "x")
+		}).
+		Retry(0, time.Second, NewCommand("true")).
+		Render()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"1INVALID"`)
+	assert.Contains(t, err.Error(), `"also-invalid"`)
+	assert.Contains(t, err.Error(), "at least one attempt")
+
+	_, err = NewScript().Export("1INVALID", "x").DaggerCMD()
+	require.Error(t, err)
+}
+
+func TestScriptDaggerCMD(t *testing.T) {
+	cmd, err := NewScript().WithSetOptions("-e").Run("echo", "hi there").DaggerCMD()
+
+	require.NoError(t, err)
+	assert.Equal(t, types.DaggerCMD{"sh", "-c", "set -e\necho 'hi there'"}, cmd)
+}
+
+func TestScriptExecution(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "tmp file")
+	out := filepath.Join(dir, "out.txt")
+	content := "it's $HOME\n`not run`\n"
+
+	cmd, err := NewScript().
+		WithSetOptions("-e").
+		CleanupOnExit(tmp).
+		Cd(dir).
+		Run("touch", tmp).
+		Export("GREETING", "it's $HOME").
+		If(`[ -n "$GREETING" ]`, func(s *Script) {
+			s.WriteFile(out, content)
+		}).
+		Retry(2, 0, NewCommand("test", "-f", out)).
+		DaggerCMD()
+	require.NoError(t, err)
+
+	output, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(written))
+	assert.NoFileExists(t, tmp)
+
+	cmd, err = NewScript().Retry(2, 0, NewCommand("false")).DaggerCMD()
+	require.NoError(t, err)
+
+	output, err = exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "command failed after 2 attempts: false")
+}
+
+func TestScriptWriteScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "install.sh")
+
+	require.NoError(t, NewScript().WithSetOptions("-ex").Run("echo", "ok").WriteScript(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\nset -ex\necho ok\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Mode()&0o100 != 0, "script should be executable")
+
+	err = NewScript().Export("bad name", "x").WriteScript(path)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "invalid script"))
+}
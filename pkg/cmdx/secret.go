@@ -0,0 +1,126 @@
+package cmdx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RedactedMask replaces the secret values masked by Redact.
+const RedactedMask = "***"
+
+// SecretBinding binds a secret value to the environment variable its placeholders expand to.
+// The variable is meant to be set as a secret (e.g., with dagger.Container.WithSecretVariable),
+// so the value never appears in the command, the logs or the cache keys.
+type SecretBinding struct {
+	// Name is the environment variable name (e.g., "GITHUB_TOKEN").
+	Name string
+	// Value is the secret value.
+	Value string
+}
+
+// SecretRef returns a placeholder for the secret held in an environment variable, to be embedded in a
+// Command argument (e.g., "Authorization: Bearer "+ref). The placeholder is rendered as a "${GITHUB_TOKEN}"
+// expansion, so the command runs through "sh -c" and the value is read at runtime.
+// It returns an error if the name is not a valid environment variable name.
+func SecretRef(name string) (string, error) {
+	if !envNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name %q: must be a valid environment variable name", name)
+	}
+
+	return envRef(name), nil
+}
+
+// Secrets registers secret values, hands out their placeholders and keeps the companion list
+// of bindings to set in the container environment.
+//
+// Example:
+//
+//	secrets := NewSecrets()
+//	token, err := secrets.Add("GITHUB_TOKEN", os.Getenv("GITHUB_TOKEN"))
+//	if err != nil {
+//	    // handle error
+//	}
+//	cmd := NewCommand("curl", "-H", "Authorization: Bearer "+token, "https://api.github.com/user")
+//	fmt.Println(cmd.String()) // Output: curl -H "Authorization: Bearer ${GITHUB_TOKEN}" https://api.github.com/user
+//	for _, b := range secrets.Bindings() {
+//	    ctr = ctr.WithSecretVariable(b.Name, client.SetSecret(b.Name, b.Value))
+//	}
+//	ctr = ctr.WithExec(cmd.DaggerCMD())
+type Secrets struct {
+	bindings []SecretBinding
+}
+
+// NewSecrets creates an empty Secrets.
+func NewSecrets() *Secrets {
+	return &Secrets{}
+}
+
+// Add registers a secret value under an environment variable name and returns its placeholder (see SecretRef).
+// Adding a name again replaces its value.
+func (s *Secrets) Add(name, value string) (string, error) {
+	ref, err := SecretRef(name)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range s.bindings {
+		if s.bindings[i].Name == name {
+			s.bindings[i].Value = value
+			return ref, nil
+		}
+	}
+
+	s.bindings = append(s.bindings, SecretBinding{Name: name, Value: value})
+
+	return ref, nil
+}
+
+// Bindings returns a copy of the secret bindings, in the order they were added.
+func (s *Secrets) Bindings() []SecretBinding {
+	return append([]SecretBinding(nil), s.bindings...)
+}
+
+// Redact masks the registered secret values in the text. See Redact.
+func (s *Secrets) Redact(text string) string {
+	values := make([]string, len(s.bindings))
+	for i, b := range s.bindings {
+		values[i] = b.Value
+	}
+
+	return Redact(text, values...)
+}
+
+// Redact masks every occurrence of the secret values in a command string or log line with RedactedMask.
+// The shell-quoted forms of the values, as rendered by Quote and inside double quotes, are masked too.
+// Empty values are ignored, and longer values are masked first, so a secret containing another one is fully masked.
+//
+// Example:
+//
+//	fmt.Println(Redact("curl -H 'Authorization: Bearer abc123' https://example.com", "abc123"))
+//	// Output: curl -H 'Authorization: Bearer ***' https://example.com
+func Redact(text string, secrets ...string) string {
+	var forms []string
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		forms = append(forms,
+			secret,
+			strings.ReplaceAll(secret, "'", `'"'"'`),
+			strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(secret),
+		)
+	}
+
+	sort.SliceStable(forms, func(i, j int) bool {
+		return len(forms[i]) > len(forms[j])
+	})
+
+	for _, form := range forms {
+		text = strings.ReplaceAll(text, form, RedactedMask)
+	}
+
+	return text
+}
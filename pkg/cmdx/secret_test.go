@@ -0,0 +1,146 @@
+package cmdx
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestSecretsPlaceholders(t *testing.T) {
+	secrets := NewSecrets()
+
+	token, err := secrets.Add("GITHUB_TOKEN", "ghp_first")
+	require.NoError(t, err)
+
+	_, err = secrets.Add("GITHUB_TOKEN", "ghp_abc'123")
+	require.NoError(t, err)
+
+	_, err = secrets.Add("invalid-name", "x")
+	require.Error(t, err)
+
+	user, err := SecretRef("USER")
+	require.NoError(t, err)
+
+	_, err = SecretRef("X:-$(id)")
+	require.Error(t, err)
+
+	cmd := NewCommand("curl", "-H", "Authorization: Bearer "+token, "-u", user+":x", "https://api.github.com")
+	require.NoError(t, cmd.Validate())
+
+	assert.Equal(t, `curl -H "Authorization: Bearer ${GITHUB_TOKEN}" -u "${USER}:x" https://api.github.com`, cmd.String())
+	assert.Equal(t, []string{"GITHUB_TOKEN", "USER"}, cmd.SecretNames())
+	line, err := cmd.Render()
+	require.NoError(t, err)
+	assert.Equal(t, cmd.String(), line)
+	assert.Equal(t, types.DaggerCMD{"sh", "-c", line}, cmd.DaggerCMD())
+	assert.Equal(t, []SecretBinding{{Name: "GITHUB_TOKEN", Value: "ghp_abc'123"}}, secrets.Bindings())
+	assert.NotContains(t, cmd.String(), "ghp_abc")
+
+	plain := NewCommand("echo", "a b")
+	assert.Empty(t, plain.SecretNames())
+	assert.Equal(t, types.DaggerCMD{"echo", "a b"}, plain.DaggerCMD())
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	echo := NewCommand("printf", "%s", "Bearer "+token).DaggerCMD()
+	shell := exec.Command(echo[0], echo[1:]...)
+	shell.Env = []string{"GITHUB_TOKEN=ghp_abc'123"}
+
+	output, err := shell.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Equal(t, "Bearer ghp_abc'123", string(output))
+}
+
+func TestCommandInvalidPlaceholders(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+	}{
+		{"Command substitution", []string{"echo", "x\x00X:-$(id)\x00y"}},
+		{"Unpaired marker", []string{"echo", "x\x00X"}},
+		{"Invalid name next to a valid one", []string{"echo", "\x00OK\x00", "\x00A:-`id`\x00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewCommand(tt.argv[0], tt.argv[1:]...)
+
+			require.Error(t, cmd.Validate())
+			assert.Empty(t, cmd.SecretNames())
+			assert.Equal(t, types.DaggerCMD(tt.argv), cmd.DaggerCMD())
+			assert.NotContains(t, cmd.String(), "${")
+			assert.NotContains(t, cmd.String(), "\x00", "String must not leak placeholder markers")
+
+			_, err := cmd.Render()
+			assert.ErrorContains(t, err, "invalid command")
+
+			_, err = cmd.ShellCMD()
+			assert.ErrorContains(t, err, "invalid command")
+
+			_, err = NewScript().RunCommand(cmd).Render()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		secrets  []string
+		expected string
+	}{
+		{
+			name:     "No secrets",
+			text:     "curl https://example.com",
+			expected: "curl https://example.com",
+		},
+		{
+			name:     "Raw value in log line",
+			text:     "token=abc123 and again abc123",
+			secrets:  []string{"abc123"},
+			expected: "token=*** and again ***",
+		},
+		{
+			name:     "Single-quoted form",
+			text:     Join("curl", "-u", "me:it's"),
+			secrets:  []string{"it's"},
+			expected: `curl -u 'me:***'`,
+		},
+		{
+			name:     "Double-quoted form",
+			text:     `export X="a\"b\$c"`,
+			secrets:  []string{`a"b$c`},
+			expected: `export X="***"`,
+		},
+		{
+			name:     "Longest secret first",
+			text:     "user=abc pass=abcdef",
+			secrets:  []string{"abc", "abcdef"},
+			expected: "user=*** pass=***",
+		},
+		{
+			name:     "Empty secret ignored",
+			text:     "nothing to hide",
+			secrets:  []string{""},
+			expected: "nothing to hide",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Redact(tt.text, tt.secrets...))
+		})
+	}
+
+	secrets := NewSecrets()
+	_, err := secrets.Add("TOKEN", "s3cr3t")
+	require.NoError(t, err)
+	assert.Equal(t, "Authorization: Bearer ***", secrets.Redact("Authorization: Bearer s3cr3t"))
+}
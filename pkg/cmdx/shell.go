@@ -0,0 +1,365 @@
+package cmdx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// shellSafeChars are the characters that never need quoting in a POSIX shell word.
+const shellSafeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+// Command is a command line kept as its exact argument vector (argv).
+// Arguments are never split or joined; quoting only happens when the command is rendered for a shell.
+type Command struct {
+	argv []string
+}
+
+// NewCommand creates a Command from a program name and its arguments, kept exactly as given,
+// including empty arguments and arguments with spaces, quotes or "$".
+//
+// Example:
+//
+//	cmd := NewCommand("echo", "it's $HOME", "a  b")
+//	fmt.Println(cmd.String())    // Output: echo 'it'"'"'s $HOME' 'a  b'
+//	fmt.Println(cmd.DaggerCMD()) // Output: [echo it's $HOME a  b]
+func NewCommand(name string, args ...string) *Command {
+	return &Command{argv: append([]string{name}, args...)}
+}
+
+// ParseCommand parses a POSIX shell command line into a Command. See Split.
+func ParseCommand(line string) (*Command, error) {
+	argv, err := Split(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(argv) == 0 {
+		return nil, errors.New("command string cannot be empty")
+	}
+
+	return &Command{argv: argv}, nil
+}
+
+// WithArgs appends arguments, kept exactly as given, and returns the command.
+func (c *Command) WithArgs(args ...string) *Command {
+	c.argv = append(c.argv, args...)
+	return c
+}
+
+// Argv returns a copy of the argument vector, program name included.
+func (c *Command) Argv() []string {
+	return append([]string(nil), c.argv...)
+}
+
+// Render renders the command as a POSIX shell command line, quoting arguments as needed.
+// Secret placeholders (see SecretRef) are rendered as "${NAME}" expansions.
+// It returns an error if an argument embeds an invalid placeholder (see Validate).
+func (c *Command) Render() (string, error) {
+	line, err := joinWords(c.argv...)
+	if err != nil {
+		return "", fmt.Errorf("invalid command: %w", err)
+	}
+
+	return line, nil
+}
+
+// String renders the command for display, as Render does. If an argument embeds an invalid
+// placeholder (see Validate), every argument is quoted as literal text, with its placeholder markers
+// shown as "\0", so the result is only meant to be read: use Render for a command line to run.
+func (c *Command) String() string {
+	if line, err := c.Render(); err == nil {
+		return line
+	}
+
+	args := make([]string, len(c.argv))
+	for i, arg := range c.argv {
+		args[i] = strings.ReplaceAll(arg, envRefMarker, `\0`)
+	}
+
+	return Join(args...)
+}
+
+// Validate checks that the secret placeholders embedded in the arguments (see SecretRef) are
+// well-formed and reference valid environment variable names.
+func (c *Command) Validate() error {
+	if _, err := envRefs(c.argv...); err != nil {
+		return fmt.Errorf("invalid command: %w", err)
+	}
+
+	return nil
+}
+
+// DaggerCMD returns the argument vector as a DaggerCMD, to be executed without a shell.
+// If the arguments embed valid secret placeholders (see SecretRef), it returns ShellCMD instead,
+// so that the shell expands them at runtime. Arguments are never rendered for a shell otherwise.
+func (c *Command) DaggerCMD() types.DaggerCMD {
+	if len(c.SecretNames()) > 0 {
+		if cmd, err := c.ShellCMD(); err == nil {
+			return cmd
+		}
+	}
+
+	return types.DaggerCMD(c.Argv())
+}
+
+// SecretNames returns the names of the environment variables referenced by the secret placeholders
+// of the arguments, in order of first use, or nil if a placeholder is invalid (see Validate).
+func (c *Command) SecretNames() []string {
+	names, err := envRefs(c.argv...)
+	if err != nil {
+		return nil
+	}
+
+	return names
+}
+
+// ShellCMD returns the command wrapped for execution by "sh -c", as a DaggerCMD
+// whose script is the correctly quoted command line (see Render).
+// It returns an error if an argument embeds an invalid placeholder (see Validate).
+func (c *Command) ShellCMD() (types.DaggerCMD, error) {
+	line, err := c.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	return types.DaggerCMD{"sh", "-c", line}, nil
+}
+
+// Quote quotes a string so that a POSIX shell reads it back as a single word, unchanged.
+// Strings made only of safe characters are returned as is; others are single-quoted,
+// with embedded single quotes rendered as '"'"'.
+//
+// Example:
+//
+//	fmt.Println(Quote("foo=bar"))     // Output: foo=bar
+//	fmt.Println(Quote("Hello World")) // Output: 'Hello World'
+//	fmt.Println(Quote("it's"))        // Output: 'it'"'"'s'
+//	fmt.Println(Quote(""))            // Output: ''
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if strings.Trim(s, shellSafeChars) == "" {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// envRefMarker delimits the environment variable references embedded in arguments by envRef.
+// A NUL byte can never appear in a real argument, so references are unambiguous.
+const envRefMarker = "\x00"
+
+// envRef returns a reference to an environment variable, to be embedded in an argument.
+// quoteWord renders it as a shell expansion, so the value never appears in the command line.
+func envRef(name string) string {
+	return envRefMarker + name + envRefMarker
+}
+
+// envRefs returns the names of the environment variables referenced in the arguments, in order of first use.
+// It returns an error if a reference is invalid (see envRefParts).
+func envRefs(args ...string) ([]string, error) {
+	var (
+		names []string
+		seen  = map[string]bool{}
+	)
+
+	for _, arg := range args {
+		parts, err := envRefParts(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 1; i < len(parts); i += 2 {
+			if !seen[parts[i]] {
+				seen[parts[i]] = true
+				names = append(names, parts[i])
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// envRefParts splits an argument around the environment variable references it embeds (see envRef):
+// the parts at odd positions are the variable names. It returns an error if a marker is unpaired or a
+// name is not a valid environment variable name, so no other text is ever rendered as an expansion.
+func envRefParts(arg string) ([]string, error) {
+	parts := strings.Split(arg, envRefMarker)
+	if len(parts)%2 == 0 {
+		return nil, fmt.Errorf("invalid argument %q: unpaired environment variable reference marker", arg)
+	}
+
+	for i := 1; i < len(parts); i += 2 {
+		if !envNameRegex.MatchString(parts[i]) {
+			return nil, fmt.Errorf("invalid argument %q: invalid environment variable reference %q", arg, parts[i])
+		}
+	}
+
+	return parts, nil
+}
+
+// quoteWord quotes an argument like Quote, except that the environment variable references
+// it embeds (see envRef) are rendered as "${NAME}" expansions inside double quotes.
+// It returns an error if a reference is invalid (see envRefParts).
+func quoteWord(arg string) (string, error) {
+	if !strings.Contains(arg, envRefMarker) {
+		return Quote(arg), nil
+	}
+
+	parts, err := envRefParts(arg)
+	if err != nil {
+		return "", err
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+	var word strings.Builder
+	word.WriteByte('"')
+
+	for i, part := range parts {
+		if i%2 == 1 {
+			word.WriteString("${" + part + "}")
+			continue
+		}
+
+		word.WriteString(escaper.Replace(part))
+	}
+
+	word.WriteByte('"')
+
+	return word.String(), nil
+}
+
+// joinWords quotes every argument with quoteWord and joins them with spaces.
+func joinWords(args ...string) (string, error) {
+	quoted := make([]string, len(args))
+
+	for i, arg := range args {
+		word, err := quoteWord(arg)
+		if err != nil {
+			return "", err
+		}
+
+		quoted[i] = word
+	}
+
+	return strings.Join(quoted, " "), nil
+}
+
+// Join quotes every argument with Quote and joins them with spaces into a POSIX shell command line.
+// Split(Join(args...)) returns args.
+func Join(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// Split splits a POSIX shell command line into its words, following the sh quoting rules:
+//   - unquoted blanks (spaces, tabs, newlines) separate words;
+//   - single quotes preserve every character up to the closing quote;
+//   - double quotes preserve every character, except that a backslash escapes "$", "`", "\"", "\\" and newline;
+//   - an unquoted backslash escapes the next character, and a backslash-newline is removed;
+//   - an unquoted "#" at the start of a word starts a comment running to the end of the line.
+//
+// Parameter expansion, command substitution and operators are not interpreted: "$HOME" and "|"
+// are kept as literal text. It returns an error for unterminated quotes or a trailing backslash.
+//
+// Example:
+//
+//	args, err := Split(`echo 'Hello, World!' "$HOME" a\ b`)
+//	// args: [echo Hello, World! $HOME a b]
+//
+//nolint:gocyclo,cyclop // A single-pass state machine is the clearest way to follow the sh grammar.
+func Split(line string) ([]string, error) {
+	var (
+		words   = []string{}
+		word    strings.Builder
+		inWord  bool
+		flushFn = func() {
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		}
+	)
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flushFn()
+		case c == '#' && !inWord:
+			for i < len(line) && line[i] != '\n' {
+				i++
+			}
+		case c == '\\':
+			if i+1 >= len(line) {
+				return nil, errors.New("unterminated escape: trailing backslash")
+			}
+
+			i++
+			if line[i] != '\n' {
+				word.WriteByte(line[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote at position %d", i)
+			}
+
+			word.WriteString(line[i+1 : i+1+end])
+			inWord, i = true, i+1+end
+		case c == '"':
+			end, err := readDoubleQuoted(line, i+1, &word)
+			if err != nil {
+				return nil, fmt.Errorf("%w at position %d", err, i)
+			}
+
+			inWord, i = true, end
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	flushFn()
+
+	return words, nil
+}
+
+// readDoubleQuoted writes the content of a double-quoted string starting at start to the word,
+// and returns the position of the closing quote.
+func readDoubleQuoted(line string, start int, word *strings.Builder) (int, error) {
+	for i := start; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 < len(line) && strings.IndexByte("$`\"\\\n", line[i+1]) >= 0 {
+				i++
+				if line[i] != '\n' {
+					word.WriteByte(line[i])
+				}
+
+				continue
+			}
+
+			word.WriteByte(c)
+		default:
+			word.WriteByte(c)
+		}
+	}
+
+	return 0, errors.New("unterminated double quote")
+}
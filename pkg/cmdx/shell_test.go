@@ -0,0 +1,152 @@
+package cmdx
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"", "''"},
+		{"plan", "plan"},
+		{"--var-file=prod.tfvars", "--var-file=prod.tfvars"},
+		{"Hello, World!", "'Hello, World!'"},
+		{"it's", `'it'"'"'s'`},
+		{"$HOME", "'$HOME'"},
+		{`say "hi"`, `'say "hi"'`},
+		{"a\nb", "'a\nb'"},
+		{"*.go", "'*.go'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Quote(tt.arg))
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected []string
+		hasError bool
+	}{
+		{"Empty", "", []string{}, false},
+		{"Blanks", " \t\n ", []string{}, false},
+		{"Plain words", "go run main.go", []string{"go", "run", "main.go"}, false},
+		{"Single quotes", `echo 'a  "b" $c'`, []string{"echo", `a  "b" $c`}, false},
+		{"Double quotes", `echo "a 'b' \$c \\ \x"`, []string{"echo", `a 'b' $c \ \x`}, false},
+		{"Escaped space", `cat my\ file`, []string{"cat", "my file"}, false},
+		{"Adjacent quotes", `a'b'"c"d`, []string{"abcd"}, false},
+		{"Empty quoted argument", `printf '' ""`, []string{"printf", "", ""}, false},
+		{"Line continuation", "echo a \\\n b", []string{"echo", "a", "b"}, false},
+		{"Comment", "echo a # comment\necho b", []string{"echo", "a", "echo", "b"}, false},
+		{"Hash inside word", "echo a#b", []string{"echo", "a#b"}, false},
+		{"Operators kept literal", "a | b && c", []string{"a", "|", "b", "&&", "c"}, false},
+		{"Unterminated single quote", "echo 'a", nil, true},
+		{"Unterminated double quote", `echo "a`, nil, true},
+		{"Trailing backslash", `echo a\`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Split(tt.line)
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cmd := NewCommand("echo", "it's $HOME", "a  b", "").WithArgs("--flag")
+
+	assert.Equal(t, types.DaggerCMD{"echo", "it's $HOME", "a  b", "", "--flag"}, cmd.DaggerCMD())
+	assert.Equal(t, `echo 'it'"'"'s $HOME' 'a  b' '' --flag`, cmd.String())
+
+	line, err := cmd.Render()
+	assert.NoError(t, err)
+	assert.Equal(t, cmd.String(), line)
+
+	shell, err := cmd.ShellCMD()
+	assert.NoError(t, err)
+	assert.Equal(t, types.DaggerCMD{"sh", "-c", line}, shell)
+
+	argv := cmd.Argv()
+	argv[0] = "changed"
+	assert.Equal(t, "echo", cmd.Argv()[0], "Argv must return a copy")
+
+	parsed, err := ParseCommand(cmd.String())
+	assert.NoError(t, err)
+	assert.Equal(t, cmd.Argv(), parsed.Argv())
+
+	_, err = ParseCommand("  # only a comment")
+	assert.Error(t, err)
+}
+
+// shellAlphabet biases generated arguments towards the characters that need quoting.
+const shellAlphabet = " \t\n'\"\\$`#*?[]{}()<>|&;!~=-_./:abcXYZ019\x00é"
+
+// shellArgs is a random argument vector for property tests.
+type shellArgs []string
+
+// Generate implements quick.Generator.
+func (shellArgs) Generate(r *rand.Rand, size int) reflect.Value {
+	args := make(shellArgs, r.Intn(size+1))
+	for i := range args {
+		var b strings.Builder
+		for n := r.Intn(size + 1); n > 0; n-- {
+			b.WriteByte(shellAlphabet[r.Intn(len(shellAlphabet))])
+		}
+
+		args[i] = b.String()
+	}
+
+	return reflect.ValueOf(args)
+}
+
+func TestJoinSplitRoundTrip(t *testing.T) {
+	roundTrip := func(args shellArgs) bool {
+		parsed, err := Split(Join(args...))
+		return err == nil && len(parsed) == len(args) && (len(args) == 0 || reflect.DeepEqual([]string(args), parsed))
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+
+	arbitrary := func(args []string) bool {
+		parsed, err := Split(Join(args...))
+		return err == nil && len(parsed) == len(args) && (len(args) == 0 || reflect.DeepEqual(args, parsed))
+	}
+
+	if err := quick.Check(arbitrary, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuoteSingleWord(t *testing.T) {
+	singleWord := func(arg string) bool {
+		parsed, err := Split(Quote(arg))
+		return err == nil && len(parsed) == 1 && parsed[0] == arg
+	}
+
+	if err := quick.Check(singleWord, nil); err != nil {
+		t.Error(err)
+	}
+}
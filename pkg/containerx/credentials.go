@@ -0,0 +1,333 @@
+package containerx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dagger.io/dagger"
+)
+
+const (
+	// dockerConfigFileName is the name of the Docker CLI configuration file.
+	dockerConfigFileName = "config.json"
+	// dockerHubServerAddress is the server address the Docker CLI stores Docker Hub credentials under.
+	dockerHubServerAddress = "https://index.docker.io/v1/"
+	// credentialHelperPrefix is the prefix of docker-credential helper executables.
+	credentialHelperPrefix = "docker-credential-"
+	// DefaultCredentialHelperTimeout is how long a docker-credential helper may run before it is killed.
+	DefaultCredentialHelperTimeout = 30 * time.Second
+)
+
+// dockerHubHosts are the hosts that are aliases of DefaultRegistry in Docker configuration files.
+var dockerHubHosts = map[string]bool{
+	DefaultRegistry:       true,
+	legacyDefaultRegistry: true,
+	dockerHubRegistryHost: true,
+}
+
+// DockerConfig is the subset of a Docker CLI configuration file (~/.docker/config.json)
+// used to resolve registry credentials.
+type DockerConfig struct {
+	// Auths maps registry addresses to their stored credentials.
+	Auths map[string]DockerAuthConfig `json:"auths,omitempty"`
+	// CredHelpers maps registry hosts to the credential helper (e.g., "ecr-login") that holds their credentials.
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+	// CredsStore is the default credential helper (e.g., "desktop", "osxkeychain").
+	CredsStore string `json:"credsStore,omitempty"`
+
+	// helperTimeout bounds the run of credential helpers; DefaultCredentialHelperTimeout if zero.
+	helperTimeout time.Duration
+}
+
+// DockerAuthConfig holds the credentials of a registry stored in a Docker configuration file.
+type DockerAuthConfig struct {
+	// Auth is the base64-encoded "username:password" pair.
+	Auth string `json:"auth,omitempty"`
+	// Username is the username, when not encoded in Auth.
+	Username string `json:"username,omitempty"`
+	// Password is the password, when not encoded in Auth.
+	Password string `json:"password,omitempty"`
+	// IdentityToken is an identity token used instead of a password.
+	IdentityToken string `json:"identitytoken,omitempty"`
+	// RegistryToken is a bearer token sent to the registry as is.
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// credentialHelperResponse is the output of "docker-credential-<helper> get".
+type credentialHelperResponse struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// DockerConfigDir returns the Docker configuration directory: the DOCKER_CONFIG
+// environment variable if set, otherwise ~/.docker.
+func DockerConfigDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".docker"), nil
+}
+
+// LoadDefaultDockerConfig loads config.json from the Docker configuration directory (see DockerConfigDir).
+// A missing file is not an error: it returns an empty configuration, meaning anonymous access.
+//
+// Example:
+//
+//	cfg, err := LoadDefaultDockerConfig()
+//	if err != nil {
+//	    // handle error
+//	}
+//	resolver := NewResolver().WithCredentials(cfg.CredentialFunc())
+func LoadDefaultDockerConfig() (*DockerConfig, error) {
+	dir, err := DockerConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadDockerConfig(filepath.Join(dir, dockerConfigFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &DockerConfig{}, nil
+	}
+
+	return cfg, err
+}
+
+// LoadDockerConfig reads and parses the Docker configuration file at the given path.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config %s: %w", path, err)
+	}
+
+	cfg, err := ParseDockerConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// ParseDockerConfig parses the content of a Docker configuration file.
+func ParseDockerConfig(data []byte) (*DockerConfig, error) {
+	var cfg DockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Credentials returns the username and secret of a registry (e.g., "ghcr.io", "docker.io").
+//
+// The lookup follows the Docker CLI: a registry-specific credential helper (credHelpers) wins,
+// then the default credential store (credsStore), then the credentials stored in auths.
+// Credential helpers are invoked as "docker-credential-<helper> get" following the
+// docker-credential protocol. Empty credentials, without error, mean anonymous access.
+func (c *DockerConfig) Credentials(registry string) (username, secret string, err error) {
+	if helper := c.credentialHelper(registry); helper != "" {
+		return runCredentialHelper(helper, serverAddress(registry), c.credentialHelperTimeout())
+	}
+
+	if c.CredsStore != "" {
+		return runCredentialHelper(c.CredsStore, serverAddress(registry), c.credentialHelperTimeout())
+	}
+
+	auth, ok := c.authConfig(registry)
+	if !ok {
+		return "", "", nil
+	}
+
+	return auth.credentials()
+}
+
+// CredentialsFor returns the username and secret of the registry of an image reference.
+func (c *DockerConfig) CredentialsFor(ref *Reference) (username, secret string, err error) {
+	return c.Credentials(ref.Registry)
+}
+
+// CredentialFunc returns the configuration as a CredentialFunc, e.g. for Resolver.WithCredentials.
+func (c *DockerConfig) CredentialFunc() CredentialFunc {
+	return c.Credentials
+}
+
+// WithHelperTimeout sets how long a credential helper may run before it is killed and the lookup fails.
+// A zero or negative timeout restores DefaultCredentialHelperTimeout.
+func (c *DockerConfig) WithHelperTimeout(timeout time.Duration) *DockerConfig {
+	c.helperTimeout = timeout
+	return c
+}
+
+// credentialHelperTimeout returns the timeout of credential helpers.
+func (c *DockerConfig) credentialHelperTimeout() time.Duration {
+	if c.helperTimeout <= 0 {
+		return DefaultCredentialHelperTimeout
+	}
+
+	return c.helperTimeout
+}
+
+// credentialHelper returns the registry-specific credential helper, if any.
+func (c *DockerConfig) credentialHelper(registry string) string {
+	for key, helper := range c.CredHelpers {
+		if normalizeRegistryAddress(key) == normalizeRegistryAddress(registry) {
+			return helper
+		}
+	}
+
+	return ""
+}
+
+// authConfig returns the auths entry of the registry, if any.
+func (c *DockerConfig) authConfig(registry string) (DockerAuthConfig, bool) {
+	if auth, ok := c.Auths[serverAddress(registry)]; ok {
+		return auth, true
+	}
+
+	for key, auth := range c.Auths {
+		if normalizeRegistryAddress(key) == normalizeRegistryAddress(registry) {
+			return auth, true
+		}
+	}
+
+	return DockerAuthConfig{}, false
+}
+
+// credentials decodes the username and secret of the auths entry.
+func (a DockerAuthConfig) credentials() (string, string, error) {
+	username, password := a.Username, a.Password
+
+	if a.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid auth entry: %w", err)
+		}
+
+		var ok bool
+		if username, password, ok = strings.Cut(string(decoded), ":"); !ok {
+			return "", "", errors.New("invalid auth entry: expected username:password")
+		}
+	}
+
+	switch {
+	case a.IdentityToken != "":
+		return username, a.IdentityToken, nil
+	case a.RegistryToken != "":
+		return username, a.RegistryToken, nil
+	default:
+		return username, password, nil
+	}
+}
+
+// runCredentialHelper gets the credentials of a server from a docker-credential helper.
+// A helper that has no credentials for the server means anonymous access.
+// The helper is killed, and an error returned, if it runs longer than the timeout.
+func runCredentialHelper(helper, server string, timeout time.Duration) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, credentialHelperPrefix+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	// Do not wait for processes the helper started, which may hold its output open.
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", "", fmt.Errorf("credential helper %s%s timed out after %s", credentialHelperPrefix, helper, timeout)
+		}
+
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, "credentials not found") {
+			return "", "", nil
+		}
+
+		return "", "", fmt.Errorf("credential helper %s%s failed: %w: %s", credentialHelperPrefix, helper, err, output)
+	}
+
+	var resp credentialHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return "", "", fmt.Errorf("invalid output of credential helper %s%s: %w", credentialHelperPrefix, helper, err)
+	}
+
+	return resp.Username, resp.Secret, nil
+}
+
+// serverAddress returns the address credentials of the registry are stored under.
+func serverAddress(registry string) string {
+	if dockerHubHosts[registry] {
+		return dockerHubServerAddress
+	}
+
+	return registry
+}
+
+// normalizeRegistryAddress reduces a registry address, as found in Docker configuration files
+// (e.g., "https://index.docker.io/v1/"), to its host, mapping Docker Hub aliases to DefaultRegistry.
+func normalizeRegistryAddress(address string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(address, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	if dockerHubHosts[host] {
+		return DefaultRegistry
+	}
+
+	return host
+}
+
+// WithRegistryAuth authenticates the container to the registry of the image reference, using the
+// credentials returned by the credential function (e.g., DockerConfig.CredentialFunc).
+// The secret is passed to Dagger as a secret, never as plain text. If the registry has no
+// credentials, the container is returned unchanged.
+//
+// Example:
+//
+//	cfg, err := LoadDefaultDockerConfig()
+//	if err != nil {
+//	    // handle error
+//	}
+//	ref, _ := Parse("ghcr.io/org/app:v1")
+//	ctr, err := WithRegistryAuth(client, client.Container(), ref, cfg.CredentialFunc())
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr = ctr.From(ref.String())
+func WithRegistryAuth(client *dagger.Client, ctr *dagger.Container, ref *Reference,
+	credentials CredentialFunc) (*dagger.Container, error) {
+	username, secret, err := credentials(ref.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for %s: %w", ref.Registry, err)
+	}
+
+	if username == "" && secret == "" {
+		return ctr, nil
+	}
+
+	return ctr.WithRegistryAuth(ref.Registry, username, client.SetSecret(registryAuthSecretName(ref.Registry), secret)), nil
+}
+
+// registryAuthSecretName returns the name of the Dagger secret holding the credentials of the registry.
+// The registry is hashed, so that distinct registries (e.g., "a.b:5000" and "a-b-5000") never share a name.
+func registryAuthSecretName(registry string) string {
+	sum := sha256.Sum256([]byte(registry))
+	return "registry-auth-" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package containerx
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCredentialHelper installs a docker-credential-<name> executable on PATH that
+// answers for the given server and reports missing credentials for any other.
+func fakeCredentialHelper(t *testing.T, name, server, username, secret string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+[ "$1" = "get" ] || exit 1
+read -r server
+if [ "$server" = "` + server + `" ]; then
+  printf '{"ServerURL":"%s","Username":"` + username + `","Secret":"` + secret + `"}' "$server"
+  exit 0
+fi
+echo "credentials not found in native keychain"
+exit 1
+`
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, credentialHelperPrefix+name), []byte(script), 0o700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	fakeCredentialHelper(t, "fake-ecr", "123456789012.dkr.ecr.us-east-1.amazonaws.com", "AWS", "ecr-token")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("octocat:ghp_secret"))
+	hub := base64.StdEncoding.EncodeToString([]byte("hubuser:hubpass"))
+
+	cfg, err := ParseDockerConfig([]byte(`{
+  "auths": {
+    "ghcr.io": {"auth": "` + encoded + `"},
+    "https://index.docker.io/v1/": {"auth": "` + hub + `"},
+    "https://quay.io": {"username": "robot", "password": "quay-pass"},
+    "registry.example.com": {"auth": "` + hub + `", "identitytoken": "refresh-token"}
+  },
+  "credHelpers": {"123456789012.dkr.ecr.us-east-1.amazonaws.com": "fake-ecr"}
+}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		registry string
+		username string
+		secret   string
+	}{
+		{"Encoded auth", "ghcr.io", "octocat", "ghp_secret"},
+		{"Docker Hub", "docker.io", "hubuser", "hubpass"},
+		{"Docker Hub alias", "registry-1.docker.io", "hubuser", "hubpass"},
+		{"Address with scheme", "quay.io", "robot", "quay-pass"},
+		{"Identity token", "registry.example.com", "hubuser", "refresh-token"},
+		{"Credential helper", "123456789012.dkr.ecr.us-east-1.amazonaws.com", "AWS", "ecr-token"},
+		{"Unknown registry", "gcr.io", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, secret, err := cfg.Credentials(tt.registry)
+			require.NoError(t, err)
+			assert.Equal(t, tt.username, username)
+			assert.Equal(t, tt.secret, secret)
+		})
+	}
+}
+
+func TestDockerConfigCredsStore(t *testing.T) {
+	fakeCredentialHelper(t, "fake-store", dockerHubServerAddress, "storeuser", "storepass")
+
+	cfg := &DockerConfig{CredsStore: "fake-store", Auths: map[string]DockerAuthConfig{dockerHubServerAddress: {}}}
+
+	ref, err := Parse("alpine:3.20")
+	require.NoError(t, err)
+
+	username, secret, err := cfg.CredentialsFor(ref)
+	require.NoError(t, err)
+	assert.Equal(t, "storeuser", username)
+	assert.Equal(t, "storepass", secret)
+
+	username, secret, err = cfg.CredentialFunc()("ghcr.io")
+	require.NoError(t, err, "missing credentials in the store mean anonymous access")
+	assert.Empty(t, username)
+	assert.Empty(t, secret)
+
+	cfg.CredsStore = "does-not-exist"
+	_, _, err = cfg.Credentials("ghcr.io")
+	assert.ErrorContains(t, err, "docker-credential-does-not-exist")
+}
+
+func TestDockerConfigCredentialHelperTimeout(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\nsleep 30\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, credentialHelperPrefix+"hung"), []byte(script), 0o700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := (&DockerConfig{CredsStore: "hung"}).WithHelperTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, _, err := cfg.Credentials("ghcr.io")
+
+	assert.ErrorContains(t, err, "docker-credential-hung timed out after 100ms")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestRegistryAuthSecretName(t *testing.T) {
+	names := map[string]string{}
+
+	for _, registry := range []string{"a.b:5000", "a-b-5000", "a.b-5000", "ghcr.io", "ghcr-io"} {
+		name := registryAuthSecretName(registry)
+		assert.True(t, strings.HasPrefix(name, "registry-auth-"), name)
+
+		if other, ok := names[name]; ok {
+			t.Errorf("registries %s and %s share the secret name %s", other, registry, name)
+		}
+
+		names[name] = registry
+	}
+
+	assert.Equal(t, registryAuthSecretName("ghcr.io"), registryAuthSecretName("ghcr.io"))
+}
+
+func TestLoadDefaultDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	cfg, err := LoadDefaultDockerConfig()
+	require.NoError(t, err, "a missing config file means anonymous access")
+	assert.Empty(t, cfg.Auths)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, dockerConfigFileName),
+		[]byte(`{"auths": {"ghcr.io": {"username": "u", "password": "p"}}}`), 0o600))
+
+	cfg, err = LoadDefaultDockerConfig()
+	require.NoError(t, err)
+
+	username, secret, err := cfg.Credentials("ghcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, "u", username)
+	assert.Equal(t, "p", secret)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, dockerConfigFileName), []byte(`{`), 0o600))
+	_, err = LoadDefaultDockerConfig()
+	assert.Error(t, err)
+
+	_, _, err = (&DockerConfig{Auths: map[string]DockerAuthConfig{"ghcr.io": {Auth: "not base64!"}}}).Credentials("ghcr.io")
+	assert.Error(t, err)
+}
@@ -10,6 +10,8 @@
 // The main functionalities provided by this package include:
 // - Setting default image names and versions if they are empty.
 // - Constructing full image URLs from provided options, with support for fallback values.
+// - Enforcing an image policy (allowed registries, pinned versions, mirrors) on the image URLs.
+// - Applying ordered container commands (types.ContainerCommand) to a container as a Plan.
 //
 // Example usage:
 //
@@ -43,6 +45,7 @@ import (
 
 // SetDefaultImageNameIfEmpty returns a fallback image if the provided image is empty.
 // If both the provided image and the fallback image are empty, it returns the default image from fixtures.
+// To govern the defaults of every module in one place, set an ImagePolicy with a DefaultImage instead.
 func SetDefaultImageNameIfEmpty(image, fallbackImage string) string {
 	if image != "" {
 		return image
@@ -65,6 +68,8 @@ func SetDefaultImageVersionIfEmpty(version, fallbackVersion string) string {
 	return "latest"
 }
 
+// NewBaseContainerOpts holds the image options of a base container.
+// Use GetImageURL to build the image URL, or Reference to get it as a parsed Reference.
 type NewBaseContainerOpts struct {
 	// Image is the name of the image to use.
 	Image string
@@ -74,6 +79,9 @@ type NewBaseContainerOpts struct {
 	FallbackImage string
 	// FallBackVersion is the version of the fallback image to use if the primary image is empty.
 	FallBackVersion string
+	// Policy is the image policy applied to the image URL. If nil, the policy set with
+	// SetDefaultImagePolicy, if any, is applied.
+	Policy *ImagePolicy
 }
 
 // GetImageURL constructs the full image URL from the provided options.
@@ -87,9 +95,16 @@ type NewBaseContainerOpts struct {
 //   - opts (*NewBaseContainerOpts): A pointer to a NewBaseContainerOpts struct containing
 //     the image name, version, fallback image name, and fallback version.
 //
+// If the version is a digest (e.g., "sha256:..."), the image is pinned to it ("image@sha256:...").
+//
+// The image policy of the options (or the default one, see SetDefaultImagePolicy) is then applied:
+// the image is rewritten through the policy mirrors and checked against its rules. The policy
+// default image and version take precedence over the built-in defaults. Without a policy, the
+// image URL is returned as constructed.
+//
 // Returns:
 //   - (string, error): Returns the constructed image URL as a string and an error if the
-//     options pointer is nil.
+//     options pointer is nil or the image violates the image policy (an *ImagePolicyError).
 //
 // Example:
 //
@@ -109,10 +124,72 @@ func GetImageURL(opts *NewBaseContainerOpts) (string, error) {
 		return "", fmt.Errorf("failed to create base container: opts is nil")
 	}
 
-	image := SetDefaultImageNameIfEmpty(opts.Image, opts.FallbackImage)
-	version := SetDefaultImageVersionIfEmpty(opts.Version, opts.FallBackVersion)
+	policy := opts.Policy
+	if policy == nil {
+		policy = DefaultImagePolicy()
+	}
+
+	image := SetDefaultImageNameIfEmpty(opts.Image, firstNonEmpty(opts.FallbackImage, policy.defaultImage()))
+	version := SetDefaultImageVersionIfEmpty(opts.Version, firstNonEmpty(opts.FallBackVersion, policy.defaultVersion()))
+
+	imageURL := fmt.Sprintf("%s:%s", image, version)
+	if validateReferenceDigest(version) == nil {
+		imageURL = fmt.Sprintf("%s@%s", image, version)
+	}
+
+	if policy.isZero() {
+		return imageURL, nil
+	}
+
+	ref, err := policy.ApplyString(imageURL)
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Familiar(), nil
+}
+
+// firstNonEmpty returns the first non-empty value.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// GetImageReference constructs the image URL from the provided options, exactly as GetImageURL does,
+// and parses it into a normalized Reference.
+//
+// Parameters:
+//   - opts (*NewBaseContainerOpts): A pointer to a NewBaseContainerOpts struct containing
+//     the image name, version, fallback image name, and fallback version.
+//
+// Returns:
+//   - (*Reference, error): Returns the parsed reference and an error if the options pointer is nil
+//     or the resulting image URL is not a valid reference.
+//
+// Example:
+//
+//	ref, err := GetImageReference(&NewBaseContainerOpts{Image: "golang", Version: "1.22"})
+//	if err != nil {
+//	    log.Fatalf("Error: %v", err)
+//	}
+//	fmt.Println(ref.String()) // Output: docker.io/library/golang:1.22
+func GetImageReference(opts *NewBaseContainerOpts) (*Reference, error) {
+	imageURL, err := GetImageURL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(imageURL)
+}
 
-	return fmt.Sprintf("%s:%s", image, version), nil
+// Reference returns the options as a parsed Reference. See GetImageReference.
+func (o *NewBaseContainerOpts) Reference() (*Reference, error) {
+	return GetImageReference(o)
 }
 
 // ValidateImageURL verifies the correctness and validity of a given Docker image URL.
@@ -139,6 +216,8 @@ func GetImageURL(opts *NewBaseContainerOpts) (string, error) {
 // **Returns:**
 // - `bool`: Returns `true` if the `imageURL` passes all validation checks; otherwise, returns `false`.
 // - `error`: Provides an error detailing the reason for validation failure, if any.
+//
+// Use Parse to validate references against the full distribution reference grammar.
 func ValidateImageURL(imageURL string) (bool, error) {
 	if imageURL == "" {
 		return false, fmt.Errorf("image URL cannot be empty")
@@ -0,0 +1,209 @@
+package containerx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Excoriate/daggerx/pkg/filex"
+)
+
+// MirrorTable is a table of registry mirrors, such as pull-through caches, that image references
+// are rewritten to. It is usually shipped as a YAML file alongside a module:
+//
+//	mirrors:
+//	  - source: docker.io
+//	    mirror: cache.internal/dockerhub
+//	    rewrites:
+//	      - from: library
+//	        to: official
+//	  - source: ghcr.io/my-org
+//	    mirror: cache.internal/ghcr/my-org
+type MirrorTable struct {
+	// Mirrors lists the mirror rules. When several rules match a reference, the one
+	// with the longest source prefix wins.
+	Mirrors []MirrorRule `yaml:"mirrors" json:"mirrors"`
+}
+
+// MirrorRule rewrites the references starting with a source prefix to a mirror prefix.
+type MirrorRule struct {
+	// Source is the registry (e.g., "docker.io") or repository prefix (e.g., "ghcr.io/my-org") to mirror.
+	Source string `yaml:"source" json:"source"`
+	// Mirror is the registry or repository prefix the matching references are rewritten to
+	// (e.g., "cache.internal/dockerhub").
+	Mirror string `yaml:"mirror" json:"mirror"`
+	// Rewrites are optional rewrites of the repository path below the source prefix.
+	// The first matching rewrite is applied.
+	Rewrites []PathRewrite `yaml:"rewrites,omitempty" json:"rewrites,omitempty"`
+}
+
+// PathRewrite replaces a leading repository path prefix with another one.
+type PathRewrite struct {
+	// From is the repository path prefix to replace (e.g., "library").
+	From string `yaml:"from" json:"from"`
+	// To is the replacement prefix. An empty value removes the prefix.
+	To string `yaml:"to" json:"to"`
+}
+
+// LoadMirrorTable reads and validates the mirror table YAML file at the given path.
+// The file must have a .yaml or .yml extension, exist, and have content.
+//
+// Example:
+//
+//	table, err := LoadMirrorTable("mirrors.yaml")
+//	if err != nil {
+//	    // handle error
+//	}
+//	image, err := table.RewriteString("alpine:3.20")
+//	fmt.Println(image) // Output: cache.internal/dockerhub/official/alpine:3.20
+func LoadMirrorTable(path string) (*MirrorTable, error) {
+	var table MirrorTable
+	if err := filex.ValidateYAML(path, &table); err != nil {
+		return nil, fmt.Errorf("failed to load mirror table %s: %w", path, err)
+	}
+
+	if err := table.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mirror table %s: %w", path, err)
+	}
+
+	return &table, nil
+}
+
+// Validate checks that every rule has a valid source and mirror prefix, and that no two rules
+// have the same source (e.g., "docker.io" and "index.docker.io"), which would make the rewrite ambiguous.
+func (t *MirrorTable) Validate() error {
+	sources := make(map[string]int, len(t.Mirrors))
+
+	for i, rule := range t.Mirrors {
+		if rule.Source == "" || rule.Mirror == "" {
+			return fmt.Errorf("mirror %d: source and mirror are required", i)
+		}
+
+		source, err := normalizeMirrorPrefix(rule.Source)
+		if err != nil {
+			return fmt.Errorf("mirror %d: invalid source: %w", i, err)
+		}
+
+		if j, ok := sources[source]; ok {
+			return fmt.Errorf("mirror %d: source %s is already mirrored by mirror %d", i, source, j)
+		}
+
+		sources[source] = i
+
+		if _, err := normalizeMirrorPrefix(rule.Mirror); err != nil {
+			return fmt.Errorf("mirror %d: invalid mirror: %w", i, err)
+		}
+
+		for _, rw := range rule.Rewrites {
+			if strings.Trim(rw.From, "/") == "" {
+				return fmt.Errorf("mirror %d: rewrite of %q has an empty from prefix", i, rw.To)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Rewrite returns the reference rewritten through the mirror rule with the longest matching
+// source prefix. The tag and digest are kept intact. If no rule matches, the reference is returned unchanged.
+// A table whose rules share a source is rejected, see Validate.
+func (t *MirrorTable) Rewrite(ref *Reference) (*Reference, error) {
+	if t == nil {
+		return ref, nil
+	}
+
+	var (
+		best       *MirrorRule
+		bestSource string
+	)
+
+	sources := make(map[string]int, len(t.Mirrors))
+
+	for i := range t.Mirrors {
+		source, err := normalizeMirrorPrefix(t.Mirrors[i].Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror source %q: %w", t.Mirrors[i].Source, err)
+		}
+
+		if j, ok := sources[source]; ok {
+			return nil, fmt.Errorf("mirror %d: source %s is already mirrored by mirror %d", i, source, j)
+		}
+
+		sources[source] = i
+
+		if hasPathPrefix(ref.Name(), source) && len(source) > len(bestSource) {
+			best, bestSource = &t.Mirrors[i], source
+		}
+	}
+
+	if best == nil {
+		return ref, nil
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(ref.Name(), bestSource), "/")
+
+	for _, rw := range best.Rewrites {
+		from := strings.Trim(rw.From, "/")
+		if hasPathPrefix(path, from) {
+			rest := strings.TrimPrefix(strings.TrimPrefix(path, from), "/")
+			path = strings.Trim(strings.Trim(rw.To, "/")+"/"+rest, "/")
+			break
+		}
+	}
+
+	mirror := strings.TrimSuffix(best.Mirror, "/")
+	if path != "" {
+		mirror += "/" + path
+	}
+
+	rewritten, err := Parse(mirror + ref.suffix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s through mirror %s: %w", ref, best.Mirror, err)
+	}
+
+	return rewritten, nil
+}
+
+// RewriteString parses the image reference and rewrites it through the mirror table.
+// The result is in its familiar form (see Reference.Familiar).
+func (t *MirrorTable) RewriteString(image string) (string, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return "", err
+	}
+
+	rewritten, err := t.Rewrite(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return rewritten.Familiar(), nil
+}
+
+// normalizeMirrorPrefix normalizes a registry or repository prefix (e.g., "index.docker.io/library")
+// to the form of Reference.Name ("docker.io/library").
+func normalizeMirrorPrefix(prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	registry, path, _ := strings.Cut(prefix, "/")
+	if !domainRegex.MatchString(registry) {
+		return "", fmt.Errorf("invalid registry: %s", registry)
+	}
+
+	registry = normalizeRegistryAddress(registry)
+	if path == "" {
+		return registry, nil
+	}
+
+	for _, component := range strings.Split(path, "/") {
+		if !pathComponentRegex.MatchString(component) {
+			return "", fmt.Errorf("invalid repository path: %s", path)
+		}
+	}
+
+	return registry + "/" + path, nil
+}
+
+// hasPathPrefix reports whether the path starts with the prefix on a path component boundary.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,155 @@
+package containerx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMirrorTable = `mirrors:
+  - source: docker.io
+    mirror: cache.internal/dockerhub
+    rewrites:
+      - from: library
+        to: official
+  - source: index.docker.io/bitnami
+    mirror: cache.internal/bitnami/
+    rewrites:
+      - from: charts
+        to: ""
+  - source: ghcr.io/my-org
+    mirror: cache.internal/ghcr/my-org
+`
+
+func writeMirrorTable(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestMirrorTableRewrite(t *testing.T) {
+	table, err := LoadMirrorTable(writeMirrorTable(t, "mirrors.yaml", testMirrorTable))
+	require.NoError(t, err)
+	require.Len(t, table.Mirrors, 3)
+
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{"Official image with path rewrite", "alpine:3.20", "cache.internal/dockerhub/official/alpine:3.20"},
+		{"Docker Hub user image", "grafana/grafana:11.0.0", "cache.internal/dockerhub/grafana/grafana:11.0.0"},
+		{"Longest source prefix wins", "bitnami/redis:7", "cache.internal/bitnami/redis:7"},
+		{"Rewrite removes prefix", "bitnami/charts/redis:7", "cache.internal/bitnami/redis:7"},
+		{"Digest kept", "ghcr.io/my-org/app@" + testDigest, "cache.internal/ghcr/my-org/app@" + testDigest},
+		{"Tag and digest kept", "ghcr.io/my-org/app:v1@" + testDigest, "cache.internal/ghcr/my-org/app:v1@" + testDigest},
+		{"Prefix on component boundary", "ghcr.io/my-organization/app:v1", "ghcr.io/my-organization/app:v1"},
+		{"No matching rule", "quay.io/org/app:v1", "quay.io/org/app:v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.RewriteString(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMirrorTableRewriteWholeRepository(t *testing.T) {
+	table := &MirrorTable{Mirrors: []MirrorRule{
+		{Source: "docker.io/library/app", Mirror: "cache.internal/app"},
+		{
+			Source:   "ghcr.io/my-org",
+			Mirror:   "cache.internal/tool/",
+			Rewrites: []PathRewrite{{From: "tools/cli", To: ""}},
+		},
+	}}
+	require.NoError(t, table.Validate())
+
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{"Source is the whole repository", "app:v1", "cache.internal/app:v1"},
+		{"Source is the whole repository with digest", "app@" + testDigest, "cache.internal/app@" + testDigest},
+		{"Rewrite consumes the whole path", "ghcr.io/my-org/tools/cli:v2", "cache.internal/tool:v2"},
+		{"Rewrite keeps the rest of the path", "ghcr.io/my-org/tools/cli/sub:v2", "cache.internal/tool/sub:v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.RewriteString(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadMirrorTableInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		contains string
+	}{
+		{"Wrong extension", "mirrors.json", testMirrorTable, "invalid YAML file extension"},
+		{"Empty file", "mirrors.yaml", "", "YAML file is empty"},
+		{"Malformed YAML", "mirrors.yaml", "mirrors: [", "failed to load mirror table"},
+		{"Missing mirror", "mirrors.yaml", "mirrors:\n  - source: docker.io\n", "source and mirror are required"},
+		{"Invalid source", "mirrors.yaml", "mirrors:\n  - source: bad..registry\n    mirror: cache.internal\n", "invalid source"},
+		{"Invalid mirror path", "mirrors.yaml", "mirrors:\n  - source: docker.io\n    mirror: cache.internal/Upper\n", "invalid mirror"},
+		{
+			"Duplicate source", "mirrors.yaml",
+			"mirrors:\n  - source: docker.io\n    mirror: a.internal\n  - source: index.docker.io/\n    mirror: b.internal\n",
+			"source docker.io is already mirrored by mirror 0",
+		},
+		{
+			"Empty rewrite", "mirrors.yaml",
+			"mirrors:\n  - source: docker.io\n    mirror: cache.internal\n    rewrites:\n      - to: x\n",
+			"empty from prefix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMirrorTable(writeMirrorTable(t, tt.file, tt.content))
+			assert.ErrorContains(t, err, tt.contains)
+		})
+	}
+}
+
+func TestGetImageURLWithMirrorTable(t *testing.T) {
+	table, err := LoadMirrorTable(writeMirrorTable(t, "mirrors.yml", testMirrorTable))
+	require.NoError(t, err)
+
+	url, err := GetImageURL(&NewBaseContainerOpts{
+		Image:   "golang",
+		Version: testDigest,
+		Policy:  &ImagePolicy{MirrorTable: table, AllowedRegistries: []string{"cache.internal"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cache.internal/dockerhub/official/golang@"+testDigest, url)
+
+	var nilTable *MirrorTable
+	got, err := nilTable.RewriteString("alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "alpine", got)
+}
+
+func TestMirrorTableRewriteAmbiguous(t *testing.T) {
+	table := &MirrorTable{Mirrors: []MirrorRule{
+		{Source: "ghcr.io/my-org", Mirror: "a.internal/my-org"},
+		{Source: "ghcr.io/my-org/", Mirror: "b.internal/my-org"},
+	}}
+
+	_, err := table.RewriteString("ghcr.io/my-org/app:v1")
+	assert.ErrorContains(t, err, "source ghcr.io/my-org is already mirrored by mirror 0")
+}
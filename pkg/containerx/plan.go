@@ -0,0 +1,269 @@
+package containerx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger.io/dagger"
+
+	"github.com/Excoriate/daggerx/pkg/cmdx"
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// PlanStepStatus is the outcome of a plan step.
+type PlanStepStatus string
+
+const (
+	// PlanStepApplied means the command was added to the container.
+	PlanStepApplied PlanStepStatus = "applied"
+	// PlanStepSkipped means the skip condition of the step held.
+	PlanStepSkipped PlanStepStatus = "skipped"
+	// PlanStepFailed means the command of a continue-on-error step failed, and the container was left unchanged.
+	PlanStepFailed PlanStepStatus = "failed"
+)
+
+// PlanStep is a named step of a Plan.
+type PlanStep struct {
+	// Name identifies the step in the dry-run and the results. Unnamed steps are named "step-<position>".
+	Name string
+	// Command is the command to run, with its focus flag and WithExec options.
+	Command types.ContainerCommand
+	// SkipIf, when set, is evaluated when the plan is applied; the step is skipped if it returns true.
+	SkipIf func() bool
+	// SkipReason describes the skip condition in the dry-run (e.g., "terraform already installed"),
+	// which never evaluates SkipIf.
+	SkipReason string
+	// ContinueOnError runs the command eagerly; if it fails, the failure is recorded and the
+	// next steps run on the container as it was before the step.
+	ContinueOnError bool
+}
+
+// PlanStepResult is the outcome of a step applied by Plan.Apply.
+type PlanStepResult struct {
+	// Name is the step name.
+	Name string
+	// Status is the step outcome.
+	Status PlanStepStatus
+	// Err is the error of a failed continue-on-error step.
+	Err error
+}
+
+// Plan is an ordered list of container commands applied to a *dagger.Container with WithExec.
+//
+// Example:
+//
+//	plan := NewPlan().
+//	    WithCommand("deps", types.ContainerCommand{CMD: types.DaggerCMD{"apk", "add", "git"}}).
+//	    WithStep(PlanStep{
+//	        Name:            "lint",
+//	        Command:         types.ContainerCommand{CMD: types.DaggerCMD{"golangci-lint", "run"}, EnableFocus: true},
+//	        ContinueOnError: true,
+//	    })
+//	fmt.Println(plan.DryRun())
+//	ctr, results, err := plan.Apply(ctx, client.Container().From("golang:1.22-alpine"))
+type Plan struct {
+	steps []PlanStep
+}
+
+// NewPlan creates a plan running the commands in order, as unnamed steps.
+func NewPlan(commands ...types.ContainerCommand) *Plan {
+	p := &Plan{}
+	for _, cmd := range commands {
+		p.WithStep(PlanStep{Command: cmd})
+	}
+
+	return p
+}
+
+// WithCommand appends a named step running the command.
+func (p *Plan) WithCommand(name string, cmd types.ContainerCommand) *Plan {
+	return p.WithStep(PlanStep{Name: name, Command: cmd})
+}
+
+// WithStep appends a step.
+func (p *Plan) WithStep(step PlanStep) *Plan {
+	p.steps = append(p.steps, step)
+	return p
+}
+
+// Steps returns a copy of the steps, unnamed steps named after their position.
+func (p *Plan) Steps() []PlanStep {
+	steps := make([]PlanStep, len(p.steps))
+	for i, step := range p.steps {
+		step.Name = stepName(i, step)
+		steps[i] = step
+	}
+
+	return steps
+}
+
+// Validate checks that every step has a command, unless it runs the container entrypoint,
+// and that step names are unique.
+func (p *Plan) Validate() error {
+	var (
+		errs []error
+		seen = map[string]bool{}
+	)
+
+	for i, step := range p.Steps() {
+		emptyCMD := len(step.Command.CMD) == 0 || step.Command.CMD[0] == ""
+		if emptyCMD && !step.Command.ContainerCMDOptions.UseEntrypoint {
+			errs = append(errs, fmt.Errorf("step %d (%s): command cannot be empty", i+1, step.Name))
+		}
+
+		if seen[step.Name] {
+			errs = append(errs, fmt.Errorf("step %d: duplicate step name %q", i+1, step.Name))
+		}
+
+		seen[step.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// Apply validates the plan and applies its steps, in order, to the container. Each command is added with
+// WithExec and its options; when EnableFocus is set, it is wrapped in WithFocus and WithoutFocus, so
+// only that step is focused.
+//
+// Steps are lazy, as any WithExec, except continue-on-error steps, which are evaluated
+// with Sync so that their failure can be recorded and skipped.
+//
+// It returns the resulting container and the result of every step.
+func (p *Plan) Apply(ctx context.Context, ctr *dagger.Container) (*dagger.Container, []PlanStepResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid plan: %w", err)
+	}
+
+	results := make([]PlanStepResult, 0, len(p.steps))
+
+	for _, step := range p.Steps() {
+		if step.SkipIf != nil && step.SkipIf() {
+			results = append(results, PlanStepResult{Name: step.Name, Status: PlanStepSkipped})
+			continue
+		}
+
+		next := withCommand(ctr, step.Command)
+
+		if step.ContinueOnError {
+			if _, err := next.Sync(ctx); err != nil {
+				results = append(results, PlanStepResult{Name: step.Name, Status: PlanStepFailed, Err: err})
+				continue
+			}
+		}
+
+		ctr = next
+		results = append(results, PlanStepResult{Name: step.Name, Status: PlanStepApplied})
+	}
+
+	return ctr, results, nil
+}
+
+// DryRun returns a printable description of the plan, one line per step, with the command
+// rendered as a shell command line and its options, flags and skip condition.
+//
+// Example:
+//
+//	fmt.Println(plan.DryRun())
+//	// Output:
+//	// 1. deps: apk add git
+//	// 2. lint: golangci-lint run [focus, continue-on-error]
+//	// 3. step-3: terraform version [skip if: terraform not required]
+func (p *Plan) DryRun() string {
+	lines := make([]string, 0, len(p.steps))
+
+	for i, step := range p.Steps() {
+		line := fmt.Sprintf("%d. %s: %s", i+1, step.Name, cmdx.Join(step.Command.CMD...))
+
+		if flags := stepFlags(step); len(flags) > 0 {
+			line += " [" + strings.Join(flags, ", ") + "]"
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// String returns the dry-run of the plan. See DryRun.
+func (p *Plan) String() string {
+	return p.DryRun()
+}
+
+// stepName returns the step name, or "step-<position>" for unnamed steps.
+func stepName(index int, step PlanStep) string {
+	if step.Name != "" {
+		return step.Name
+	}
+
+	return fmt.Sprintf("step-%d", index+1)
+}
+
+// execContainer is the part of *dagger.Container used to add a step command.
+type execContainer[T any] interface {
+	WithFocus() T
+	WithoutFocus() T
+	WithExec(args []string, opts ...dagger.ContainerWithExecOpts) T
+}
+
+// withCommand adds the command to the container with WithExec and its options, focused
+// between WithFocus and WithoutFocus when EnableFocus is set.
+func withCommand[T execContainer[T]](ctr T, cmd types.ContainerCommand) T {
+	if !cmd.EnableFocus {
+		return ctr.WithExec(cmd.CMD, cmd.ContainerCMDOptions)
+	}
+
+	return ctr.WithFocus().WithExec(cmd.CMD, cmd.ContainerCMDOptions).WithoutFocus()
+}
+
+// stepFlags describes the options, flags and skip condition of a step for the dry-run.
+// The skip condition is described by its SkipReason, and never evaluated.
+func stepFlags(step PlanStep) []string {
+	var flags []string
+
+	opts := step.Command.ContainerCMDOptions
+
+	if step.Command.EnableFocus {
+		flags = append(flags, "focus")
+	}
+
+	if step.ContinueOnError {
+		flags = append(flags, "continue-on-error")
+	}
+
+	if opts.UseEntrypoint {
+		flags = append(flags, "use-entrypoint")
+	}
+
+	if opts.Stdin != "" {
+		flags = append(flags, "stdin")
+	}
+
+	if opts.RedirectStdout != "" {
+		flags = append(flags, "stdout > "+opts.RedirectStdout)
+	}
+
+	if opts.RedirectStderr != "" {
+		flags = append(flags, "stderr > "+opts.RedirectStderr)
+	}
+
+	if opts.InsecureRootCapabilities {
+		flags = append(flags, "insecure-root-capabilities")
+	}
+
+	if opts.ExperimentalPrivilegedNesting {
+		flags = append(flags, "privileged-nesting")
+	}
+
+	if step.SkipIf != nil {
+		reason := step.SkipReason
+		if reason == "" {
+			reason = "condition"
+		}
+
+		flags = append(flags, "skip if: "+reason)
+	}
+
+	return flags
+}
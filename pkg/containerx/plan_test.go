@@ -0,0 +1,162 @@
+package containerx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestPlanDryRun(t *testing.T) {
+	evaluated := 0
+	plan := NewPlan(types.ContainerCommand{CMD: types.DaggerCMD{"apk", "add", "git"}}).
+		WithCommand("lint", types.ContainerCommand{
+			CMD:         types.DaggerCMD{"golangci-lint", "run", "--timeout", "5 m"},
+			EnableFocus: true,
+		}).
+		WithStep(PlanStep{
+			Name: "report",
+			Command: types.ContainerCommand{
+				CMD: types.DaggerCMD{"cat"},
+				ContainerCMDOptions: dagger.ContainerWithExecOpts{
+					Stdin:          "report",
+					RedirectStdout: "/tmp/report.txt",
+				},
+			},
+			ContinueOnError: true,
+		}).
+		WithStep(PlanStep{
+			Name:    "terraform",
+			Command: types.ContainerCommand{CMD: types.DaggerCMD{"terraform", "version"}},
+			SkipIf: func() bool {
+				evaluated++
+				return true
+			},
+			SkipReason: "terraform not required",
+		}).
+		WithStep(PlanStep{
+			Name:    "docs",
+			Command: types.ContainerCommand{CMD: types.DaggerCMD{"make", "docs"}},
+			SkipIf:  func() bool { return false },
+		})
+
+	expected := `1. step-1: apk add git
+2. lint: golangci-lint run --timeout '5 m' [focus]
+3. report: cat [continue-on-error, stdin, stdout > /tmp/report.txt]
+4. terraform: terraform version [skip if: terraform not required]
+5. docs: make docs [skip if: condition]`
+
+	require.NoError(t, plan.Validate())
+	assert.Equal(t, expected, plan.DryRun())
+	assert.Equal(t, expected, plan.String())
+	assert.Zero(t, evaluated, "the dry-run must not evaluate skip conditions")
+
+	names := make([]string, 0, 4)
+	for _, step := range plan.Steps() {
+		names = append(names, step.Name)
+	}
+
+	assert.Equal(t, []string{"step-1", "lint", "report", "terraform", "docs"}, names)
+}
+
+// recordedContainer records the calls withCommand makes.
+type recordedContainer struct {
+	calls []string
+}
+
+func (c *recordedContainer) WithFocus() *recordedContainer {
+	c.calls = append(c.calls, "focus")
+	return c
+}
+
+func (c *recordedContainer) WithoutFocus() *recordedContainer {
+	c.calls = append(c.calls, "unfocus")
+	return c
+}
+
+func (c *recordedContainer) WithExec(args []string, _ ...dagger.ContainerWithExecOpts) *recordedContainer {
+	c.calls = append(c.calls, "exec "+strings.Join(args, " "))
+	return c
+}
+
+func TestWithCommandFocusDoesNotCarryOver(t *testing.T) {
+	ctr := &recordedContainer{}
+
+	for _, cmd := range []types.ContainerCommand{
+		{CMD: types.DaggerCMD{"go", "test"}, EnableFocus: true},
+		{CMD: types.DaggerCMD{"go", "build"}},
+	} {
+		ctr = withCommand(ctr, cmd)
+	}
+
+	assert.Equal(t, []string{"focus", "exec go test", "unfocus", "exec go build"}, ctr.calls)
+}
+
+func TestPlanValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    *Plan
+		errText string
+	}{
+		{
+			name:    "Empty command",
+			plan:    NewPlan(types.ContainerCommand{}),
+			errText: "step 1 (step-1): command cannot be empty",
+		},
+		{
+			name: "Duplicate names",
+			plan: NewPlan().
+				WithCommand("build", types.ContainerCommand{CMD: types.DaggerCMD{"go", "build"}}).
+				WithCommand("build", types.ContainerCommand{CMD: types.DaggerCMD{"go", "test"}}),
+			errText: `step 2: duplicate step name "build"`,
+		},
+		{
+			name: "Entrypoint without arguments",
+			plan: NewPlan(types.ContainerCommand{
+				ContainerCMDOptions: dagger.ContainerWithExecOpts{UseEntrypoint: true},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Validate()
+			if tt.errText == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errText)
+
+			_, _, err = tt.plan.Apply(context.Background(), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid plan")
+		})
+	}
+}
+
+func TestPlanApplySkippedSteps(t *testing.T) {
+	evaluated := 0
+	plan := NewPlan().WithStep(PlanStep{
+		Name:    "skipped",
+		Command: types.ContainerCommand{CMD: types.DaggerCMD{"false"}},
+		SkipIf: func() bool {
+			evaluated++
+			return true
+		},
+	})
+
+	// No WithExec is added, so the container is returned as is.
+	ctr, results, err := plan.Apply(context.Background(), nil)
+
+	require.NoError(t, err)
+	assert.Nil(t, ctr)
+	assert.Equal(t, []PlanStepResult{{Name: "skipped", Status: PlanStepSkipped}}, results)
+	assert.Equal(t, 1, evaluated)
+}
@@ -0,0 +1,245 @@
+package containerx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd/platforms"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// maxImageConfigSize is the maximum size of an image configuration fetched to check its platform.
+const maxImageConfigSize = 4 << 20
+
+// ImageIndex is an OCI image index or a Docker manifest list: the list of the
+// platform-specific manifests of a multi-platform image.
+type ImageIndex struct {
+	// SchemaVersion is the schema version of the document (2).
+	SchemaVersion int `json:"schemaVersion"`
+	// MediaType is MediaTypeOCIIndex or MediaTypeDockerManifestList.
+	MediaType string `json:"mediaType,omitempty"`
+	// Manifests lists the platform-specific manifests.
+	Manifests []ManifestDescriptor `json:"manifests"`
+}
+
+// ManifestDescriptor describes a manifest listed in an image index.
+type ManifestDescriptor struct {
+	// MediaType is the media type of the manifest.
+	MediaType string `json:"mediaType"`
+	// Digest is the digest of the manifest (e.g., "sha256:...").
+	Digest string `json:"digest"`
+	// Size is the size of the manifest in bytes.
+	Size int64 `json:"size"`
+	// Platform is the platform the manifest is built for. It is nil for non-image
+	// manifests, such as attestations.
+	Platform *specs.Platform `json:"platform,omitempty"`
+	// Annotations holds the annotations of the descriptor.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ParseImageIndex parses an OCI image index or Docker manifest list.
+// It returns an error if the document is a single-platform manifest or has no manifests.
+func ParseImageIndex(data []byte) (*ImageIndex, error) {
+	var index ImageIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+
+	switch index.MediaType {
+	case MediaTypeOCIIndex, MediaTypeDockerManifestList, "":
+	default:
+		return nil, fmt.Errorf("unsupported image index media type: %s", index.MediaType)
+	}
+
+	if len(index.Manifests) == 0 {
+		return nil, fmt.Errorf("image index has no manifests")
+	}
+
+	return &index, nil
+}
+
+// Platforms returns the platforms of the index manifests (e.g., "linux/arm/v7"), in index order.
+func (i *ImageIndex) Platforms() []string {
+	var result []string
+
+	for _, m := range i.Manifests {
+		if m.Platform != nil {
+			result = append(result, platforms.Format(*m.Platform))
+		}
+	}
+
+	return result
+}
+
+// SelectPlatform returns the manifest of the index built for the requested platform
+// (e.g., "linux/amd64", "linux/arm/v7", "arm64"), as parsed by containerd's platforms package.
+//
+// When no manifest matches the requested variant exactly, the closest compatible variant of the
+// same architecture is selected (e.g., "linux/arm/v6" for "linux/arm/v7", "linux/amd64" for
+// "linux/amd64/v3"). Manifests of other architectures are never selected.
+//
+// Example:
+//
+//	index, err := ParseImageIndex(data)
+//	if err != nil {
+//	    // handle error
+//	}
+//	manifest, err := index.SelectPlatform("linux/arm64")
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(manifest.Digest)
+func (i *ImageIndex) SelectPlatform(platform string) (*ManifestDescriptor, error) {
+	requested, err := platforms.Parse(platform)
+	if err != nil {
+		return nil, fmt.Errorf("invalid platform %q: %w", platform, err)
+	}
+
+	requested = platforms.Normalize(requested)
+	matcher := platforms.Only(requested)
+
+	var candidates []ManifestDescriptor
+
+	for _, m := range i.Manifests {
+		if m.Platform != nil && platformMatches(requested, matcher, *m.Platform) {
+			candidates = append(candidates, m)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no manifest for platform %s, available platforms: %v",
+			platforms.Format(requested), i.Platforms())
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return matcher.Less(*candidates[a].Platform, *candidates[b].Platform)
+	})
+
+	return &candidates[0], nil
+}
+
+// ResolvePlatform returns the digest of the platform-specific manifest the reference points to.
+// If the reference points to a single-platform manifest, the platform of its image configuration
+// is checked against the requested one, and the manifest digest is returned if it matches.
+// Parameters of the manifest media type (e.g., "; charset=utf-8") are ignored.
+func (r *Resolver) ResolvePlatform(ctx context.Context, ref *Reference, platform string) (string, error) {
+	data, mediaType, digest, err := r.FetchManifest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+
+	if mediaType == MediaTypeOCIManifest || mediaType == MediaTypeDockerManifest {
+		if err := r.checkManifestPlatform(ctx, ref, data, platform); err != nil {
+			return "", err
+		}
+
+		return digest, nil
+	}
+
+	index, err := ParseImageIndex(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image index of %s: %w", ref, err)
+	}
+
+	manifest, err := index.SelectPlatform(platform)
+	if err != nil {
+		return "", fmt.Errorf("failed to select platform of %s: %w", ref, err)
+	}
+
+	return manifest.Digest, nil
+}
+
+// PinPlatform parses the image reference and returns it pinned to the digest of its
+// manifest for the requested platform, as "repository@sha256:...".
+func (r *Resolver) PinPlatform(ctx context.Context, image, platform string) (*Reference, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := r.ResolvePlatform(ctx, ref, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reference{Registry: ref.Registry, Repository: ref.Repository, Digest: digest}, nil
+}
+
+// checkManifestPlatform checks that the image configuration of the single-platform manifest
+// was built for the requested platform, with the same rules as ImageIndex.SelectPlatform.
+func (r *Resolver) checkManifestPlatform(ctx context.Context, ref *Reference, manifest []byte, platform string) error {
+	requested, err := platforms.Parse(platform)
+	if err != nil {
+		return fmt.Errorf("invalid platform %q: %w", platform, err)
+	}
+
+	requested = platforms.Normalize(requested)
+
+	var m specs.Manifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return fmt.Errorf("failed to parse manifest of %s: %w", ref, err)
+	}
+
+	if validateReferenceDigest(m.Config.Digest.String()) != nil {
+		return fmt.Errorf("manifest of %s has no valid config digest", ref)
+	}
+
+	config, err := r.fetchBlob(ctx, ref, m.Config.Digest.String(), maxImageConfigSize)
+	if err != nil {
+		return err
+	}
+
+	var image specs.Image
+	if err := json.Unmarshal(config, &image); err != nil {
+		return fmt.Errorf("failed to parse image configuration of %s: %w", ref, err)
+	}
+
+	if !platformMatches(requested, platforms.Only(requested), image.Platform) {
+		return fmt.Errorf("image %s is built for platform %s, not %s",
+			ref, platforms.Format(image.Platform), platforms.Format(requested))
+	}
+
+	return nil
+}
+
+// fetchBlob downloads a blob of the reference repository, of at most 'limit' bytes, and checks its digest.
+func (r *Resolver) fetchBlob(ctx context.Context, ref *Reference, digest string, limit int64) ([]byte, error) {
+	resp, err := r.blobRequest(ctx, ref, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s of %s: %w", digest, ref, err)
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("blob %s of %s exceeds %d bytes", digest, ref, limit)
+	}
+
+	sum := sha256.Sum256(data)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); strings.HasPrefix(digest, "sha256:") && actual != digest {
+		return nil, fmt.Errorf("blob %s of %s does not match its digest: got %s", digest, ref, actual)
+	}
+
+	return data, nil
+}
+
+// platformMatches reports whether the candidate platform can run images of the requested (normalized)
+// platform: same architecture, and a compatible variant according to the matcher.
+func platformMatches(requested specs.Platform, matcher platforms.Matcher, candidate specs.Platform) bool {
+	return platforms.Normalize(candidate).Architecture == requested.Architecture && matcher.Match(candidate)
+}
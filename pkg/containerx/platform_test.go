@@ -0,0 +1,193 @@
+package containerx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testImageIndex = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:amd64", "size": 1,
+     "platform": {"architecture": "amd64", "os": "linux"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:armv6", "size": 1,
+     "platform": {"architecture": "arm", "os": "linux", "variant": "v6"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:armv5", "size": 1,
+     "platform": {"architecture": "arm", "os": "linux", "variant": "v5"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:arm64", "size": 1,
+     "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:attestation", "size": 1,
+     "platform": {"architecture": "unknown", "os": "unknown"},
+     "annotations": {"vnd.docker.reference.type": "attestation-manifest"}}
+  ]
+}`
+
+func TestImageIndexSelectPlatform(t *testing.T) {
+	index, err := ParseImageIndex([]byte(testImageIndex))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		platform string
+		digest   string
+		wantErr  bool
+	}{
+		{"Exact match", "linux/amd64", "sha256:amd64", false},
+		{"Architecture only", "amd64", "sha256:amd64", false},
+		{"Amd64 variant fallback", "linux/amd64/v3", "sha256:amd64", false},
+		{"Arm64 normalized variant", "linux/arm64", "sha256:arm64", false},
+		{"Aarch64 alias", "linux/aarch64", "sha256:arm64", false},
+		{"Arm variant exact", "linux/arm/v5", "sha256:armv5", false},
+		{"Arm variant fallback prefers closest", "linux/arm/v7", "sha256:armv6", false},
+		{"No fallback to other architectures", "linux/386", "", true},
+		{"No compatible variant", "linux/arm/v4", "", true},
+		{"Invalid platform", "linux/not/a/platform/at/all", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := index.SelectPlatform(tt.platform)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.digest, manifest.Digest)
+		})
+	}
+
+	assert.Equal(t, []string{"linux/amd64", "linux/arm/v6", "linux/arm/v5", "linux/arm64/v8", "unknown/unknown"},
+		index.Platforms())
+}
+
+func TestParseImageIndexInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Not JSON", "not json"},
+		{"Single manifest", `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": []}`},
+		{"No manifests", `{"schemaVersion": 2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseImageIndex([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestResolverPinPlatform(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["app:v1"] = testImageIndex
+
+	resolver := NewResolver().WithTransport(reg.server.Client().Transport)
+
+	ref, err := resolver.PinPlatform(context.Background(), reg.host()+"/app:v1", "linux/arm64")
+	require.NoError(t, err)
+	assert.Equal(t, reg.host()+"/app@sha256:arm64", ref.String())
+
+	_, err = resolver.PinPlatform(context.Background(), reg.host()+"/app:v1", "windows/amd64")
+	assert.ErrorContains(t, err, "no manifest for platform windows/amd64")
+}
+
+func TestResolverResolvePlatformSingleManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		config      string
+		platform    string
+		errText     string
+	}{
+		{
+			name: "OCI manifest", contentType: MediaTypeOCIManifest,
+			config: `{"architecture": "arm64", "os": "linux"}`, platform: "linux/arm64",
+		},
+		{
+			name: "Docker manifest", contentType: MediaTypeDockerManifest,
+			config: `{"architecture": "amd64", "os": "linux"}`, platform: "linux/amd64",
+		},
+		{
+			name: "Parameterized media type", contentType: MediaTypeOCIManifest + "; charset=utf-8",
+			config: `{"architecture": "amd64", "os": "linux"}`, platform: "linux/amd64",
+		},
+		{
+			name: "Uppercase media type", contentType: "Application/VND.OCI.Image.Manifest.v1+json",
+			config: `{"architecture": "amd64", "os": "linux"}`, platform: "linux/amd64",
+		},
+		{
+			name: "Compatible variant", contentType: MediaTypeOCIManifest,
+			config: `{"architecture": "arm", "os": "linux", "variant": "v6"}`, platform: "linux/arm/v7",
+		},
+		{
+			name: "Other architecture", contentType: MediaTypeOCIManifest,
+			config: `{"architecture": "amd64", "os": "linux"}`, platform: "linux/arm64",
+			errText: "is built for platform linux/amd64, not linux/arm64",
+		},
+		{
+			name: "Incompatible variant", contentType: MediaTypeOCIManifest,
+			config: `{"architecture": "arm", "os": "linux", "variant": "v7"}`, platform: "linux/arm/v6",
+			errText: "is built for platform linux/arm/v7, not linux/arm/v6",
+		},
+		{
+			name: "Other OS", contentType: MediaTypeOCIManifest,
+			config: `{"architecture": "amd64", "os": "windows"}`, platform: "linux/amd64",
+			errText: "is built for platform windows/amd64, not linux/amd64",
+		},
+		{
+			name: "Missing config", contentType: MediaTypeOCIManifest,
+			platform: "linux/amd64", errText: "failed to fetch blob",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := newFakeRegistry(t, true)
+			reg.contentType = tt.contentType
+
+			configDigest := reg.addBlob(tt.config)
+			if tt.config == "" {
+				delete(reg.blobs, configDigest)
+			}
+
+			reg.manifests["app:v1"] = fmt.Sprintf(
+				`{"schemaVersion": 2, "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": %q, "size": 1}}`,
+				configDigest)
+
+			resolver := NewResolver().WithTransport(reg.server.Client().Transport)
+
+			ref, err := Parse(reg.host() + "/app:v1")
+			require.NoError(t, err)
+
+			digest, err := resolver.ResolvePlatform(context.Background(), ref, tt.platform)
+			if tt.errText != "" {
+				assert.ErrorContains(t, err, tt.errText)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, reg.digestOf("app:v1"), digest)
+		})
+	}
+}
+
+func TestResolverResolvePlatformTamperedConfig(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.contentType = MediaTypeOCIManifest
+
+	configDigest := reg.addBlob(`{"architecture": "arm64", "os": "linux"}`)
+	reg.blobs[configDigest] = `{"architecture": "amd64", "os": "linux"}`
+	reg.manifests["app:v1"] = fmt.Sprintf(`{"schemaVersion": 2, "config": {"digest": %q, "size": 1}}`, configDigest)
+
+	resolver := NewResolver().WithTransport(reg.server.Client().Transport)
+
+	_, err := resolver.PinPlatform(context.Background(), reg.host()+"/app:v1", "linux/amd64")
+	assert.ErrorContains(t, err, "does not match its digest")
+}
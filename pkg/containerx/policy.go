@@ -0,0 +1,182 @@
+package containerx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// latestTag is the tag Docker uses when a reference has neither tag nor digest.
+const latestTag = "latest"
+
+// ImagePolicy is a set of rules enforced on image references, so that every module
+// resolves its images through a single governance point.
+//
+// A nil or zero-value policy allows every reference and leaves it unchanged.
+type ImagePolicy struct {
+	// DefaultImage is the image used when neither an image nor a fallback image is provided.
+	// It replaces fixtures.Image as the last-resort default.
+	DefaultImage string
+	// DefaultVersion is the version used when neither a version nor a fallback version is provided.
+	// It replaces "latest" as the last-resort default.
+	DefaultVersion string
+	// AllowedRegistries lists the registries (e.g., "ghcr.io") or repository prefixes
+	// (e.g., "ghcr.io/my-org") images may be pulled from. Empty allows any registry.
+	// It is checked after mirror rewriting.
+	AllowedRegistries []string
+	// ForbidLatest rejects references tagged "latest", or without tag and digest.
+	ForbidLatest bool
+	// RequireDigest rejects references that are not pinned to a digest.
+	RequireDigest bool
+	// Mirrors maps source registries (e.g., "docker.io") to the mirror they are pulled through
+	// (e.g., "mirror.internal" or "mirror.internal/dockerhub"). The repository path, tag and digest are kept.
+	Mirrors map[string]string
+	// MirrorTable holds additional mirror rules, with repository prefixes and path rewrites.
+	// It is combined with Mirrors; the rule with the longest matching source prefix wins.
+	MirrorTable *MirrorTable
+}
+
+// ImagePolicyError is returned when an image reference violates an ImagePolicy.
+type ImagePolicyError struct {
+	// Reference is the offending reference, after mirror rewriting.
+	Reference string
+	// Violations describes every rule the reference violates.
+	Violations []string
+}
+
+// Error returns a description of every violation.
+func (e *ImagePolicyError) Error() string {
+	return fmt.Sprintf("image %s violates the image policy: %s", e.Reference, strings.Join(e.Violations, "; "))
+}
+
+// defaultImagePolicy is the policy applied by GetImageURL when the options do not set one.
+var defaultImagePolicy atomic.Pointer[ImagePolicy]
+
+// SetDefaultImagePolicy sets the policy GetImageURL applies when NewBaseContainerOpts.Policy is nil.
+// Passing nil removes the default policy.
+//
+// Example:
+//
+//	SetDefaultImagePolicy(&ImagePolicy{
+//	    AllowedRegistries: []string{"mirror.internal", "ghcr.io/my-org"},
+//	    ForbidLatest:      true,
+//	    Mirrors:           map[string]string{"docker.io": "mirror.internal/dockerhub"},
+//	})
+func SetDefaultImagePolicy(policy *ImagePolicy) {
+	defaultImagePolicy.Store(policy)
+}
+
+// DefaultImagePolicy returns the policy set with SetDefaultImagePolicy, or nil.
+func DefaultImagePolicy() *ImagePolicy {
+	return defaultImagePolicy.Load()
+}
+
+// Apply rewrites the reference through the policy mirrors and checks it against the policy rules.
+// It returns the rewritten reference, or an *ImagePolicyError listing every violated rule.
+func (p *ImagePolicy) Apply(ref *Reference) (*Reference, error) {
+	if p == nil {
+		return ref, nil
+	}
+
+	rewritten, err := p.rewrite(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := p.violations(rewritten); len(violations) > 0 {
+		return nil, &ImagePolicyError{Reference: rewritten.String(), Violations: violations}
+	}
+
+	return rewritten, nil
+}
+
+// ApplyString parses the image reference and applies the policy to it. See Apply.
+func (p *ImagePolicy) ApplyString(image string) (*Reference, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Apply(ref)
+}
+
+// isZero reports whether the policy has no rules, so references are left untouched.
+func (p *ImagePolicy) isZero() bool {
+	return p == nil || (len(p.AllowedRegistries) == 0 && !p.ForbidLatest && !p.RequireDigest &&
+		len(p.Mirrors) == 0 && (p.MirrorTable == nil || len(p.MirrorTable.Mirrors) == 0))
+}
+
+// defaultImage returns the policy default image, if any.
+func (p *ImagePolicy) defaultImage() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.DefaultImage
+}
+
+// defaultVersion returns the policy default version, if any.
+func (p *ImagePolicy) defaultVersion() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.DefaultVersion
+}
+
+// rewrite rewrites the reference through the policy mirrors, if one matches.
+func (p *ImagePolicy) rewrite(ref *Reference) (*Reference, error) {
+	table := &MirrorTable{}
+	if p.MirrorTable != nil {
+		table.Mirrors = append(table.Mirrors, p.MirrorTable.Mirrors...)
+	}
+
+	for source, mirror := range p.Mirrors {
+		table.Mirrors = append(table.Mirrors, MirrorRule{Source: source, Mirror: mirror})
+	}
+
+	return table.Rewrite(ref)
+}
+
+// violations returns the descriptions of the rules the reference violates.
+func (p *ImagePolicy) violations(ref *Reference) []string {
+	var violations []string
+
+	if len(p.AllowedRegistries) > 0 && !p.allowed(ref) {
+		allowed := append([]string(nil), p.AllowedRegistries...)
+		sort.Strings(allowed)
+		violations = append(violations, fmt.Sprintf("registry %s is not allowed (allowed: %s)",
+			ref.Registry, strings.Join(allowed, ", ")))
+	}
+
+	if p.ForbidLatest && (ref.Tag == latestTag || (ref.Tag == "" && ref.Digest == "")) {
+		violations = append(violations, `the "latest" tag is forbidden, use a fixed version`)
+	}
+
+	if p.RequireDigest && ref.Digest == "" {
+		violations = append(violations, "a digest is required (e.g., image@sha256:...)")
+	}
+
+	return violations
+}
+
+// allowed reports whether the reference matches one of the allowed registries or repository prefixes.
+func (p *ImagePolicy) allowed(ref *Reference) bool {
+	name := ref.Name()
+
+	for _, entry := range p.AllowedRegistries {
+		registry, path, _ := strings.Cut(strings.TrimSuffix(entry, "/"), "/")
+		prefix := normalizeRegistryAddress(registry)
+
+		if path != "" {
+			prefix += "/" + path
+		}
+
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
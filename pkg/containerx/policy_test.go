@@ -0,0 +1,176 @@
+package containerx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImagePolicyApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     *ImagePolicy
+		image      string
+		want       string
+		violations int
+	}{
+		{
+			name:   "Nil policy",
+			policy: nil,
+			image:  "alpine",
+			want:   "docker.io/library/alpine",
+		},
+		{
+			name:   "Allowed registry",
+			policy: &ImagePolicy{AllowedRegistries: []string{"ghcr.io"}},
+			image:  "ghcr.io/org/app:v1",
+			want:   "ghcr.io/org/app:v1",
+		},
+		{
+			name:   "Allowed repository prefix",
+			policy: &ImagePolicy{AllowedRegistries: []string{"ghcr.io/org/"}},
+			image:  "ghcr.io/org/team/app:v1",
+			want:   "ghcr.io/org/team/app:v1",
+		},
+		{
+			name:       "Repository prefix is not a name prefix",
+			policy:     &ImagePolicy{AllowedRegistries: []string{"ghcr.io/org"}},
+			image:      "ghcr.io/organization/app:v1",
+			violations: 1,
+		},
+		{
+			name:       "Registry not allowed",
+			policy:     &ImagePolicy{AllowedRegistries: []string{"ghcr.io"}},
+			image:      "quay.io/org/app:v1",
+			violations: 1,
+		},
+		{
+			name:   "Docker Hub alias allowed",
+			policy: &ImagePolicy{AllowedRegistries: []string{"index.docker.io"}},
+			image:  "golang:1.22",
+			want:   "docker.io/library/golang:1.22",
+		},
+		{
+			name:       "Latest tag forbidden",
+			policy:     &ImagePolicy{ForbidLatest: true},
+			image:      "alpine:latest",
+			violations: 1,
+		},
+		{
+			name:       "Implicit latest forbidden",
+			policy:     &ImagePolicy{ForbidLatest: true},
+			image:      "alpine",
+			violations: 1,
+		},
+		{
+			name:   "Digest satisfies forbid latest",
+			policy: &ImagePolicy{ForbidLatest: true, RequireDigest: true},
+			image:  "alpine@" + testDigest,
+			want:   "docker.io/library/alpine@" + testDigest,
+		},
+		{
+			name:       "Digest required",
+			policy:     &ImagePolicy{RequireDigest: true},
+			image:      "alpine:3.20",
+			violations: 1,
+		},
+		{
+			name: "Mirror rewrite before allowed registries",
+			policy: &ImagePolicy{
+				AllowedRegistries: []string{"mirror.internal"},
+				Mirrors:           map[string]string{"docker.io": "mirror.internal/dockerhub/"},
+			},
+			image: "alpine:3.20@" + testDigest,
+			want:  "mirror.internal/dockerhub/library/alpine:3.20@" + testDigest,
+		},
+		{
+			name: "Every violation is reported",
+			policy: &ImagePolicy{
+				AllowedRegistries: []string{"ghcr.io"},
+				ForbidLatest:      true,
+				RequireDigest:     true,
+			},
+			image:      "alpine",
+			violations: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := tt.policy.ApplyString(tt.image)
+			if tt.violations > 0 {
+				var policyErr *ImagePolicyError
+				require.True(t, errors.As(err, &policyErr), "expected *ImagePolicyError, got %v", err)
+				assert.Len(t, policyErr.Violations, tt.violations)
+				assert.Nil(t, ref)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ref.String())
+		})
+	}
+}
+
+func TestImagePolicyErrorMessage(t *testing.T) {
+	policy := &ImagePolicy{AllowedRegistries: []string{"quay.io", "ghcr.io"}, ForbidLatest: true}
+
+	_, err := policy.ApplyString("alpine")
+	assert.EqualError(t, err, `image docker.io/library/alpine violates the image policy: `+
+		`registry docker.io is not allowed (allowed: ghcr.io, quay.io); `+
+		`the "latest" tag is forbidden, use a fixed version`)
+
+	_, err = (&ImagePolicy{Mirrors: map[string]string{"docker.io": "Invalid Mirror"}}).ApplyString("alpine")
+	assert.ErrorContains(t, err, "through mirror Invalid Mirror")
+}
+
+func TestGetImageURLWithPolicy(t *testing.T) {
+	t.Run("Options policy", func(t *testing.T) {
+		url, err := GetImageURL(&NewBaseContainerOpts{
+			Image:   "golang",
+			Version: "1.22",
+			Policy:  &ImagePolicy{Mirrors: map[string]string{"docker.io": "mirror.internal"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "mirror.internal/library/golang:1.22", url)
+	})
+
+	t.Run("Policy defaults", func(t *testing.T) {
+		url, err := GetImageURL(&NewBaseContainerOpts{
+			Policy: &ImagePolicy{DefaultImage: "ghcr.io/org/base", DefaultVersion: "1.0.0", ForbidLatest: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ghcr.io/org/base:1.0.0", url)
+	})
+
+	t.Run("Digest version", func(t *testing.T) {
+		url, err := GetImageURL(&NewBaseContainerOpts{
+			Image:   "alpine",
+			Version: testDigest,
+			Policy:  &ImagePolicy{RequireDigest: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "alpine@"+testDigest, url)
+	})
+
+	t.Run("Default policy", func(t *testing.T) {
+		SetDefaultImagePolicy(&ImagePolicy{ForbidLatest: true})
+		t.Cleanup(func() { SetDefaultImagePolicy(nil) })
+
+		_, err := GetImageURL(&NewBaseContainerOpts{Image: "alpine"})
+		var policyErr *ImagePolicyError
+		assert.ErrorAs(t, err, &policyErr)
+
+		url, err := GetImageURL(&NewBaseContainerOpts{Image: "alpine", Version: "3.20"})
+		require.NoError(t, err)
+		assert.Equal(t, "alpine:3.20", url)
+
+		// A policy set on the options takes precedence over the default one.
+		url, err = GetImageURL(&NewBaseContainerOpts{Image: "alpine", Policy: &ImagePolicy{}})
+		require.NoError(t, err)
+		assert.Equal(t, "alpine:latest", url)
+	})
+}
@@ -0,0 +1,230 @@
+package containerx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the registry used for references that do not name one.
+	DefaultRegistry = "docker.io"
+	// DefaultNamespace is the Docker Hub namespace of official images (e.g., "alpine" is "library/alpine").
+	DefaultNamespace = "library"
+	// legacyDefaultRegistry is the legacy Docker Hub host, normalized to DefaultRegistry.
+	legacyDefaultRegistry = "index.docker.io"
+	// maxNameLength is the maximum length of the registry and repository path combined.
+	maxNameLength = 255
+)
+
+// The expressions below follow the grammar of github.com/distribution/reference:
+//
+//	reference       := name [ ":" tag ] [ "@" digest ]
+//	name            := [domain '/'] remote-name
+//	domain          := host [':' port-number]
+//	remote-name     := path-component ['/' path-component]*
+//	path-component  := alpha-numeric [separator alpha-numeric]*
+//	separator       := /[_.]|__|[-]+/
+//	tag             := /[\w][\w.-]{0,127}/
+//	digest          := algorithm ":" encoded
+var (
+	pathComponentRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*$`)
+	domainRegex        = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])` +
+		`(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?$`)
+	tagRegex        = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegex     = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+	identifierRegex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+)
+
+// knownDigestLengths maps the registered digest algorithms to the length of their hex-encoded value.
+var knownDigestLengths = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+// Reference is a parsed and normalized container image reference.
+//
+// References are normalized the way Docker does: a reference without a registry points to
+// Docker Hub ("docker.io") and a single-component Docker Hub repository lives in the
+// "library" namespace, so "alpine:3.20" has Registry "docker.io" and Repository "library/alpine".
+type Reference struct {
+	// Registry is the registry host, with an optional port (e.g., "ghcr.io", "localhost:5000").
+	Registry string
+	// Repository is the repository path within the registry (e.g., "library/alpine").
+	Repository string
+	// Tag is the image tag, if any.
+	Tag string
+	// Digest is the image digest (e.g., "sha256:..."), if any.
+	Digest string
+}
+
+// Parse parses and normalizes an image reference following the distribution reference grammar.
+//
+// Unlike ValidateImageURL, it accepts any number of path components and registries without
+// a dot such as "localhost" or "localhost:5000". The first component is treated as the
+// registry when it contains a "." or ":" or is "localhost"; otherwise the reference points to Docker Hub.
+// Repository path components must be lowercase.
+//
+// Parameters:
+//   - ref (string): The image reference to parse (e.g., "alpine", "ghcr.io/org/app:v1", "app@sha256:...").
+//
+// Returns:
+//   - (*Reference, error): The parsed reference, or an error describing why the reference is invalid.
+//
+// Example:
+//
+//	ref, err := Parse("alpine:3.20")
+//	if err != nil {
+//	    log.Fatalf("Error: %v", err)
+//	}
+//	fmt.Println(ref.String())   // Output: docker.io/library/alpine:3.20
+//	fmt.Println(ref.Familiar()) // Output: alpine:3.20
+func Parse(ref string) (*Reference, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("image reference cannot be empty")
+	}
+
+	name, digest, hasDigest := strings.Cut(ref, "@")
+	if hasDigest {
+		if err := validateReferenceDigest(digest); err != nil {
+			return nil, err
+		}
+	}
+
+	var tag string
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+		if !tagRegex.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q in reference %q", tag, ref)
+		}
+	}
+
+	registry, repository, err := splitRegistry(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+	}
+
+	return &Reference{Registry: registry, Repository: repository, Tag: tag, Digest: digest}, nil
+}
+
+// splitRegistry splits a name into its normalized registry and repository path, validating both.
+func splitRegistry(name string) (string, string, error) {
+	registry, repository := DefaultRegistry, name
+
+	if first, rest, ok := strings.Cut(name, "/"); ok &&
+		(strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first) {
+		registry, repository = first, rest
+	}
+
+	if !domainRegex.MatchString(registry) {
+		return "", "", fmt.Errorf("invalid registry: %s", registry)
+	}
+
+	if registry == legacyDefaultRegistry {
+		registry = DefaultRegistry
+	}
+
+	if registry == DefaultRegistry && !strings.Contains(repository, "/") {
+		if identifierRegex.MatchString(repository) {
+			return "", "", fmt.Errorf("repository name cannot be a 64-character hexadecimal identifier")
+		}
+
+		repository = DefaultNamespace + "/" + repository
+	}
+
+	if len(registry)+1+len(repository) > maxNameLength {
+		return "", "", fmt.Errorf("repository name must not be longer than %d characters", maxNameLength)
+	}
+
+	for _, component := range strings.Split(repository, "/") {
+		if component != strings.ToLower(component) {
+			return "", "", fmt.Errorf("repository name must be lowercase: %s", repository)
+		}
+
+		if !pathComponentRegex.MatchString(component) {
+			return "", "", fmt.Errorf("invalid repository name: %s", repository)
+		}
+	}
+
+	return registry, repository, nil
+}
+
+// validateReferenceDigest checks the digest format and, for registered algorithms, the length of its value.
+func validateReferenceDigest(digest string) error {
+	if !digestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid digest: %s", digest)
+	}
+
+	algorithm, encoded, _ := strings.Cut(digest, ":")
+	if length, ok := knownDigestLengths[algorithm]; ok {
+		if len(encoded) != length || strings.Trim(encoded, "0123456789abcdef") != "" {
+			return fmt.Errorf("invalid digest: %s", digest)
+		}
+	}
+
+	return nil
+}
+
+// Name returns the fully qualified repository name, without tag or digest (e.g., "docker.io/library/alpine").
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the fully qualified reference (e.g., "docker.io/library/alpine:3.20").
+func (r *Reference) String() string {
+	return r.Name() + r.suffix()
+}
+
+// Familiar returns the shortest form of the reference, as displayed by the Docker CLI.
+// The Docker Hub registry and "library" namespace are omitted (e.g., "alpine:3.20").
+func (r *Reference) Familiar() string {
+	if r.Registry != DefaultRegistry {
+		return r.String()
+	}
+
+	repository := r.Repository
+	if rest, ok := strings.CutPrefix(repository, DefaultNamespace+"/"); ok && !strings.Contains(rest, "/") {
+		repository = rest
+	}
+
+	return repository + r.suffix()
+}
+
+// WithTag returns a copy of the reference with the given tag. The digest, if any, is kept.
+func (r *Reference) WithTag(tag string) (*Reference, error) {
+	if !tagRegex.MatchString(tag) {
+		return nil, fmt.Errorf("invalid tag: %s", tag)
+	}
+
+	ref := *r
+	ref.Tag = tag
+
+	return &ref, nil
+}
+
+// WithDigest returns a copy of the reference pinned to the given digest. The tag, if any, is kept.
+func (r *Reference) WithDigest(digest string) (*Reference, error) {
+	if err := validateReferenceDigest(digest); err != nil {
+		return nil, err
+	}
+
+	ref := *r
+	ref.Digest = digest
+
+	return &ref, nil
+}
+
+// suffix returns the ":tag" and "@digest" part of the reference.
+func (r *Reference) suffix() string {
+	var s string
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
@@ -0,0 +1,167 @@
+package containerx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDigest = "sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		ref        string
+		registry   string
+		repository string
+		tag        string
+		digest     string
+		str        string
+		familiar   string
+	}{
+		{
+			name: "Official image", ref: "alpine",
+			registry: "docker.io", repository: "library/alpine",
+			str: "docker.io/library/alpine", familiar: "alpine",
+		},
+		{
+			name: "Official image with tag", ref: "alpine:3.20",
+			registry: "docker.io", repository: "library/alpine", tag: "3.20",
+			str: "docker.io/library/alpine:3.20", familiar: "alpine:3.20",
+		},
+		{
+			name: "Docker Hub user image", ref: "bitnami/redis:7",
+			registry: "docker.io", repository: "bitnami/redis", tag: "7",
+			str: "docker.io/bitnami/redis:7", familiar: "bitnami/redis:7",
+		},
+		{
+			name: "Legacy Docker Hub host", ref: "index.docker.io/library/ubuntu:22.04",
+			registry: "docker.io", repository: "library/ubuntu", tag: "22.04",
+			str: "docker.io/library/ubuntu:22.04", familiar: "ubuntu:22.04",
+		},
+		{
+			name: "Digest only", ref: "ubuntu@" + testDigest,
+			registry: "docker.io", repository: "library/ubuntu", digest: testDigest,
+			str: "docker.io/library/ubuntu@" + testDigest, familiar: "ubuntu@" + testDigest,
+		},
+		{
+			name: "Tag and digest", ref: "ghcr.io/org/app:v1@" + testDigest,
+			registry: "ghcr.io", repository: "org/app", tag: "v1", digest: testDigest,
+			str: "ghcr.io/org/app:v1@" + testDigest, familiar: "ghcr.io/org/app:v1@" + testDigest,
+		},
+		{
+			name: "Localhost registry", ref: "localhost/app",
+			registry: "localhost", repository: "app",
+			str: "localhost/app", familiar: "localhost/app",
+		},
+		{
+			name: "Localhost registry with port", ref: "localhost:5000/team/app:dev",
+			registry: "localhost:5000", repository: "team/app", tag: "dev",
+			str: "localhost:5000/team/app:dev", familiar: "localhost:5000/team/app:dev",
+		},
+		{
+			name: "More than four components", ref: "registry.gitlab.com/group/subgroup/project/image:tag",
+			registry: "registry.gitlab.com", repository: "group/subgroup/project/image", tag: "tag",
+			str:      "registry.gitlab.com/group/subgroup/project/image:tag",
+			familiar: "registry.gitlab.com/group/subgroup/project/image:tag",
+		},
+		{
+			name: "ECR image", ref: "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:latest",
+			registry: "123456789012.dkr.ecr.us-west-2.amazonaws.com", repository: "my-app", tag: "latest",
+			str:      "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:latest",
+			familiar: "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:latest",
+		},
+		{
+			name: "Separators", ref: "quay.io/my__org/my---app.v2_x:1.0-rc.1",
+			registry: "quay.io", repository: "my__org/my---app.v2_x", tag: "1.0-rc.1",
+			str: "quay.io/my__org/my---app.v2_x:1.0-rc.1", familiar: "quay.io/my__org/my---app.v2_x:1.0-rc.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := Parse(tt.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.registry, ref.Registry)
+			assert.Equal(t, tt.repository, ref.Repository)
+			assert.Equal(t, tt.tag, ref.Tag)
+			assert.Equal(t, tt.digest, ref.Digest)
+			assert.Equal(t, tt.str, ref.String())
+			assert.Equal(t, tt.familiar, ref.Familiar())
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr string
+	}{
+		{"Empty", "", "image reference cannot be empty"},
+		{"Uppercase repository", "ghcr.io/Org/app", "repository name must be lowercase"},
+		{"Uppercase official image", "Ubuntu", "repository name must be lowercase"},
+		{"Invalid registry", "invalid..registry/image:tag", "invalid registry"},
+		{"Invalid tag", "registry.com/repo:invalid_tag!", "invalid tag"},
+		{"Tag too long", "alpine:" + strings.Repeat("a", 129), "invalid tag"},
+		{"Empty tag", "registry.com/my_repo:", "invalid tag"},
+		{"Invalid digest", "registry.com/app@sha256:not-a-valid-hash", "invalid digest"},
+		{"Short sha256 digest", "alpine@sha256:1234", "invalid digest"},
+		{"Repository with @", "invalid@repo:tag", "invalid digest"},
+		{"Leading separator", "registry.com/-app", "invalid repository name"},
+		{"Empty component", "registry.com//app", "invalid repository name"},
+		{"Hex identifier", "1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef", "hexadecimal identifier"},
+		{"Name too long", "registry.com/" + strings.Repeat("a", 250), "must not be longer than 255"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := Parse(tt.ref)
+			assert.Nil(t, ref)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestReferenceWithTagAndDigest(t *testing.T) {
+	ref, err := Parse("golang:1.22")
+	require.NoError(t, err)
+
+	tagged, err := ref.WithTag("1.23")
+	require.NoError(t, err)
+	assert.Equal(t, "golang:1.23", tagged.Familiar())
+	assert.Equal(t, "1.22", ref.Tag, "WithTag must not modify the original reference")
+
+	pinned, err := tagged.WithDigest(testDigest)
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io/library/golang:1.23@"+testDigest, pinned.String())
+	assert.Equal(t, "docker.io/library/golang", pinned.Name())
+
+	_, err = ref.WithTag("bad tag")
+	assert.Error(t, err)
+
+	_, err = ref.WithDigest("sha256:abc")
+	assert.Error(t, err)
+}
+
+func TestGetImageReference(t *testing.T) {
+	ref, err := GetImageReference(&NewBaseContainerOpts{Image: "localhost:5000/tools/builder", Version: "v1"})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000", ref.Registry)
+	assert.Equal(t, "tools/builder", ref.Repository)
+	assert.Equal(t, "v1", ref.Tag)
+
+	opts := &NewBaseContainerOpts{FallbackImage: "alpine"}
+	ref, err = opts.Reference()
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io/library/alpine:latest", ref.String())
+
+	_, err = GetImageReference(nil)
+	assert.EqualError(t, err, "failed to create base container: opts is nil")
+
+	_, err = GetImageReference(&NewBaseContainerOpts{Image: "Invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,455 @@
+package containerx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Excoriate/daggerx/pkg/fixtures"
+)
+
+// Manifest media types accepted when resolving a reference against a registry.
+const (
+	// MediaTypeOCIIndex is the media type of an OCI image index.
+	MediaTypeOCIIndex = "application/vnd.oci.image.index.v1+json"
+	// MediaTypeOCIManifest is the media type of an OCI image manifest.
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeDockerManifestList is the media type of a Docker manifest list.
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	// MediaTypeDockerManifest is the media type of a Docker image manifest.
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// dockerHubRegistryHost is the host serving the distribution API for DefaultRegistry.
+const dockerHubRegistryHost = "registry-1.docker.io"
+
+// maxManifestSize is the maximum size of a manifest downloaded by the resolver. Registries
+// are only required to accept manifests of up to 4MiB.
+const maxManifestSize = 4 << 20
+
+// maxTokenResponseSize is the maximum size of a token service response.
+const maxTokenResponseSize = 1 << 20
+
+// manifestAcceptHeader lists the manifest media types the resolver accepts, indexes first,
+// so that multi-platform images resolve to the digest of their index.
+var manifestAcceptHeader = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+// authParamRegex extracts the key="value" parameters of a WWW-Authenticate challenge.
+var authParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// CredentialFunc returns the username and secret (password or token) for a registry, e.g. "ghcr.io".
+// Empty credentials mean anonymous access.
+type CredentialFunc func(registry string) (username, secret string, err error)
+
+// Resolver resolves image references to their digest using the OCI distribution API.
+// It supports anonymous access, basic authentication and the bearer-token flow used by
+// Docker Hub, GHCR and most other registries. Tokens are cached per registry and scope.
+type Resolver struct {
+	transport   http.RoundTripper
+	credentials CredentialFunc
+	plainHTTP   map[string]bool
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewResolver creates a Resolver that uses http.DefaultTransport and anonymous access.
+//
+// Example:
+//
+//	resolver := NewResolver().WithCredentials(func(registry string) (string, string, error) {
+//	    return "user", os.Getenv("REGISTRY_TOKEN"), nil
+//	})
+//	ref, err := resolver.Pin(ctx, "ghcr.io/org/app:v1")
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(ref) // Output: ghcr.io/org/app@sha256:...
+func NewResolver() *Resolver {
+	return &Resolver{
+		transport: http.DefaultTransport,
+		plainHTTP: map[string]bool{},
+		tokens:    map[string]string{},
+	}
+}
+
+// WithTransport sets the HTTP transport used to talk to registries and token services.
+func (r *Resolver) WithTransport(transport http.RoundTripper) *Resolver {
+	r.transport = transport
+	return r
+}
+
+// WithCredentials sets the function that provides the credentials of each registry.
+func (r *Resolver) WithCredentials(credentials CredentialFunc) *Resolver {
+	r.credentials = credentials
+	return r
+}
+
+// WithPlainHTTP makes the resolver talk plain HTTP, instead of HTTPS, to the given registries (e.g., "localhost:5000").
+func (r *Resolver) WithPlainHTTP(registries ...string) *Resolver {
+	for _, registry := range registries {
+		r.plainHTTP[registry] = true
+	}
+
+	return r
+}
+
+// Resolve returns the digest of the manifest the reference points to.
+// If the reference already holds a digest, it is returned without contacting the registry.
+// For multi-platform images, this is the digest of the image index.
+func (r *Resolver) Resolve(ctx context.Context, ref *Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	resp, err := r.manifestRequest(ctx, http.MethodHead, ref)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); validateReferenceDigest(digest) == nil {
+		return digest, nil
+	}
+
+	// The registry did not return the digest, so compute it from the manifest content.
+	_, _, digest, err := r.FetchManifest(ctx, ref)
+
+	return digest, err
+}
+
+// FetchManifest downloads the manifest the reference points to.
+// It returns the manifest content, its media type and its digest.
+func (r *Resolver) FetchManifest(ctx context.Context, ref *Reference) ([]byte, string, string, error) {
+	resp, err := r.manifestRequest(ctx, http.MethodGet, ref)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read manifest of %s: %w", ref, err)
+	}
+
+	if len(data) > maxManifestSize {
+		return nil, "", "", fmt.Errorf("manifest of %s exceeds %d bytes", ref, maxManifestSize)
+	}
+
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	if ref.Digest != "" && ref.Digest != digest && strings.HasPrefix(ref.Digest, "sha256:") {
+		return nil, "", "", fmt.Errorf("manifest of %s does not match its digest: got %s", ref, digest)
+	}
+
+	return data, resp.Header.Get("Content-Type"), digest, nil
+}
+
+// Pin parses the image reference and returns it pinned to its digest, as "repository@sha256:...".
+// The tag is dropped, since the digest alone identifies the image.
+func (r *Resolver) Pin(ctx context.Context, image string) (*Reference, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reference{Registry: ref.Registry, Repository: ref.Repository, Digest: digest}, nil
+}
+
+// PinAll pins every image reference, returning the pinned references keyed by the given image.
+// All images are attempted; the returned error joins the errors of those that could not be pinned.
+func (r *Resolver) PinAll(ctx context.Context, images ...string) (map[string]*Reference, error) {
+	pinned := make(map[string]*Reference, len(images))
+
+	var errs []error
+
+	for _, image := range images {
+		ref, err := r.Pin(ctx, image)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to pin %s: %w", image, err))
+			continue
+		}
+
+		pinned[image] = ref
+	}
+
+	return pinned, errors.Join(errs...)
+}
+
+// PinDefaults pins the images returned by DefaultImages.
+func (r *Resolver) PinDefaults(ctx context.Context) (map[string]*Reference, error) {
+	return r.PinAll(ctx, DefaultImages()...)
+}
+
+// DefaultImages returns the images this module pulls by tag when no image is provided:
+// the fallback container image and the apko image.
+func DefaultImages() []string {
+	return []string{
+		fmt.Sprintf("%s:%s", fixtures.Image, fixtures.ImageVersion),
+		fmt.Sprintf("%s:%s", fixtures.ApkoImage, fixtures.ImageVersion),
+	}
+}
+
+// manifestRequest sends a manifest request for the reference, authenticating if the registry asks to.
+func (r *Resolver) manifestRequest(ctx context.Context, method string, ref *Reference) (*http.Response, error) {
+	target := ref.Tag
+	if ref.Digest != "" {
+		target = ref.Digest
+	}
+
+	if target == "" {
+		target = fixtures.ImageVersion
+	}
+
+	resp, err := r.registryRequest(ctx, method, ref, "manifests/"+target, manifestAcceptHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s: %w", ref, err)
+	}
+
+	return resp, nil
+}
+
+// blobRequest sends a request for a blob of the reference repository, authenticating if the registry asks to.
+func (r *Resolver) blobRequest(ctx context.Context, ref *Reference, digest string) (*http.Response, error) {
+	resp, err := r.registryRequest(ctx, http.MethodGet, ref, "blobs/"+digest, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blob %s of %s: %w", digest, ref, err)
+	}
+
+	return resp, nil
+}
+
+// registryRequest sends a request for a path below "/v2/<repository>/" of the reference registry.
+// It returns an error unless the registry answers with 200 OK.
+func (r *Resolver) registryRequest(ctx context.Context, method string, ref *Reference, path, accept string) (*http.Response, error) {
+	requestURL := fmt.Sprintf("%s/v2/%s/%s", r.baseURL(ref.Registry), ref.Repository, path)
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		return req, nil
+	}
+
+	resp, err := r.do(newRequest, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+// do sends the request and, if the registry answers with an authentication challenge,
+// authenticates and sends it again.
+func (r *Resolver) do(newRequest func() (*http.Request, error), ref *Reference) (*http.Response, error) {
+	client := &http.Client{Transport: r.transport}
+	scope := fmt.Sprintf("repository:%s:pull", ref.Repository)
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := r.cachedToken(ref.Registry, scope); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	authorization, err := r.authorize(req.Context(), client, ref.Registry, scope, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if req, err = newRequest(); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", authorization)
+
+	return client.Do(req)
+}
+
+// authorize answers an authentication challenge and returns the Authorization header to send.
+func (r *Resolver) authorize(ctx context.Context, client *http.Client, registry, scope, challenge string) (string, error) {
+	username, secret, err := r.lookupCredentials(registry)
+	if err != nil {
+		return "", err
+	}
+
+	scheme, _, _ := strings.Cut(challenge, " ")
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" && secret == "" {
+			return "", fmt.Errorf("registry %s requires credentials", registry)
+		}
+
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+secret)), nil
+	case "bearer":
+		token, err := r.fetchToken(ctx, client, registry, challenge, scope, username, secret)
+		if err != nil {
+			return "", err
+		}
+
+		r.mu.Lock()
+		r.tokens[registry+"|"+scope] = token
+		r.mu.Unlock()
+
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge from %s: %q", registry, challenge)
+	}
+}
+
+// fetchToken requests a bearer token from the token service named in the challenge.
+// The token service must be served over HTTPS, since the credentials of the registry are sent to it,
+// unless the registry is set to plain HTTP through WithPlainHTTP.
+func (r *Resolver) fetchToken(
+	ctx context.Context, client *http.Client, registry, challenge, scope, username, secret string,
+) (string, error) {
+	params := map[string]string{}
+	for _, m := range authParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+
+	if params["realm"] == "" {
+		return "", fmt.Errorf("authentication challenge has no realm: %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+
+	switch {
+	case tokenURL.Scheme == "https" && tokenURL.Host != "":
+	case tokenURL.Scheme == "http" && tokenURL.Host != "" && r.plainHTTP[registry]:
+	default:
+		return "", fmt.Errorf("token realm %q of %s is not an HTTPS URL", params["realm"], registry)
+	}
+
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+
+	if params["scope"] != "" {
+		scope = params["scope"]
+	}
+
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	if username != "" || secret != "" {
+		req.SetBasicAuth(username, secret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch token from %s: unexpected status %s", tokenURL.Host, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+
+	if body.Token == "" {
+		return "", errors.New("token response has no token")
+	}
+
+	return body.Token, nil
+}
+
+// lookupCredentials returns the credentials of the registry, if a credential function is set.
+func (r *Resolver) lookupCredentials(registry string) (string, string, error) {
+	if r.credentials == nil {
+		return "", "", nil
+	}
+
+	username, secret, err := r.credentials(registry)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get credentials for %s: %w", registry, err)
+	}
+
+	return username, secret, nil
+}
+
+// cachedToken returns the cached bearer token of the registry and scope, if any.
+func (r *Resolver) cachedToken(registry, scope string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tokens[registry+"|"+scope]
+}
+
+// baseURL returns the distribution API base URL of the registry.
+func (r *Resolver) baseURL(registry string) string {
+	host := registry
+	if host == DefaultRegistry {
+		host = dockerHubRegistryHost
+	}
+
+	if r.plainHTTP[registry] {
+		return "http://" + host
+	}
+
+	return "https://" + host
+}
@@ -0,0 +1,282 @@
+package containerx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry is an in-process registry serving manifests behind the bearer-token flow.
+type fakeRegistry struct {
+	server *httptest.Server
+	// manifests maps "repository:tag" to the manifest content.
+	manifests map[string]string
+	// realm, if set, replaces the token service URL advertised in authentication challenges.
+	realm string
+	// blobs maps a blob digest to its content.
+	blobs map[string]string
+	// contentType, if set, replaces the MediaTypeOCIIndex Content-Type of the manifests.
+	contentType string
+	// omitDigest makes the registry omit the Docker-Content-Digest header.
+	omitDigest bool
+	// username and password, if set, are required by the token service.
+	username, password string
+
+	tokenRequests atomic.Int32
+}
+
+func newFakeRegistry(t *testing.T, tls bool) *fakeRegistry {
+	t.Helper()
+
+	reg := &fakeRegistry{manifests: map[string]string{}, blobs: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", reg.serveToken)
+	mux.HandleFunc("/v2/", reg.serveManifest)
+
+	if tls {
+		reg.server = httptest.NewTLSServer(mux)
+	} else {
+		reg.server = httptest.NewServer(mux)
+	}
+
+	t.Cleanup(reg.server.Close)
+
+	return reg
+}
+
+func (f *fakeRegistry) host() string {
+	return f.server.Listener.Addr().String()
+}
+
+func (f *fakeRegistry) digestOf(key string) string {
+	sum := sha256.Sum256([]byte(f.manifests[key]))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addBlob stores the blob and returns its digest.
+func (f *fakeRegistry) addBlob(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	f.blobs[digest] = content
+
+	return digest
+}
+
+func (f *fakeRegistry) serveToken(w http.ResponseWriter, r *http.Request) {
+	f.tokenRequests.Add(1)
+
+	if f.username != "" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != f.username || pass != f.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if r.URL.Query().Get("service") != "fake-registry" || !strings.HasSuffix(r.URL.Query().Get("scope"), ":pull") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fmt.Fprint(w, `{"token": "secret-token"}`)
+}
+
+func (f *fakeRegistry) serveManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret-token" {
+		realm := f.server.URL + "/token"
+		if f.realm != "" {
+			realm = f.realm
+		}
+
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="fake-registry"`, realm))
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+
+	if _, digest, ok := strings.Cut(path, "/blobs/"); ok {
+		blob, ok := f.blobs[digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, blob)
+
+		return
+	}
+
+	repo, tag, _ := strings.Cut(path, "/manifests/")
+
+	manifest, ok := f.manifests[repo+":"+tag]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	contentType := MediaTypeOCIIndex
+	if f.contentType != "" {
+		contentType = f.contentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	if !f.omitDigest {
+		w.Header().Set("Docker-Content-Digest", f.digestOf(repo+":"+tag))
+	}
+
+	if r.Method == http.MethodGet {
+		fmt.Fprint(w, manifest)
+	}
+}
+
+func TestResolverPin(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["team/app:v1"] = `{"schemaVersion": 2, "manifests": []}`
+	reg.username, reg.password = "user", "pass"
+
+	resolver := NewResolver().
+		WithTransport(reg.server.Client().Transport).
+		WithCredentials(func(registry string) (string, string, error) {
+			assert.Equal(t, reg.host(), registry)
+			return "user", "pass", nil
+		})
+
+	ref, err := resolver.Pin(context.Background(), reg.host()+"/team/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, reg.host()+"/team/app@"+reg.digestOf("team/app:v1"), ref.String())
+
+	// A second resolution reuses the cached token.
+	_, err = resolver.Pin(context.Background(), reg.host()+"/team/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reg.tokenRequests.Load())
+}
+
+func TestResolverComputesDigestWithoutHeader(t *testing.T) {
+	reg := newFakeRegistry(t, false)
+	reg.manifests["app:latest"] = `{"schemaVersion": 2}`
+	reg.omitDigest = true
+
+	resolver := NewResolver().WithPlainHTTP(reg.host())
+
+	ref, err := Parse(reg.host() + "/app")
+	require.NoError(t, err)
+
+	digest, err := resolver.Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, reg.digestOf("app:latest"), digest)
+
+	data, mediaType, _, err := resolver.FetchManifest(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, `{"schemaVersion": 2}`, string(data))
+	assert.Equal(t, MediaTypeOCIIndex, mediaType)
+}
+
+func TestResolverPinAll(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["app:v1"] = `{"a": 1}`
+	reg.manifests["tools/cli:v2"] = `{"b": 2}`
+
+	resolver := NewResolver().WithTransport(reg.server.Client().Transport)
+
+	alreadyPinned := "alpine@" + testDigest
+	images := []string{reg.host() + "/app:v1", reg.host() + "/tools/cli:v2", alreadyPinned}
+
+	pinned, err := resolver.PinAll(context.Background(), images...)
+	require.NoError(t, err)
+	require.Len(t, pinned, 3)
+	assert.Equal(t, reg.digestOf("app:v1"), pinned[images[0]].Digest)
+	assert.Equal(t, reg.digestOf("tools/cli:v2"), pinned[images[1]].Digest)
+	assert.Equal(t, "docker.io/library/alpine@"+testDigest, pinned[alreadyPinned].String())
+
+	pinned, err = resolver.PinAll(context.Background(), reg.host()+"/app:v1", reg.host()+"/missing:v1", "Invalid")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing:v1")
+	assert.Contains(t, err.Error(), "unexpected status 404")
+	assert.Contains(t, err.Error(), "Invalid")
+	assert.Len(t, pinned, 1)
+}
+
+func TestResolverAuthErrors(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["app:v1"] = `{}`
+	reg.username, reg.password = "user", "pass"
+
+	resolver := NewResolver().
+		WithTransport(reg.server.Client().Transport).
+		WithCredentials(func(string) (string, string, error) { return "user", "wrong", nil })
+
+	_, err := resolver.Pin(context.Background(), reg.host()+"/app:v1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch token")
+
+	resolver = NewResolver().
+		WithTransport(reg.server.Client().Transport).
+		WithCredentials(func(string) (string, string, error) { return "", "", fmt.Errorf("no helper") })
+
+	_, err = resolver.Pin(context.Background(), reg.host()+"/app:v1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no helper")
+}
+
+func TestDefaultImages(t *testing.T) {
+	assert.Equal(t, []string{"alpine:latest", "cgr.dev/chainguard/apko:latest"}, DefaultImages())
+
+	for _, image := range DefaultImages() {
+		ref, err := Parse(image)
+		require.NoError(t, err)
+		assert.NotEmpty(t, ref.Tag)
+	}
+}
+
+func TestResolverRejectsInsecureRealm(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["app:v1"] = `{"schemaVersion": 2}`
+	reg.username, reg.password = "user", "pass"
+
+	credentials := func(string) (string, string, error) { return "user", "pass", nil }
+
+	for _, realm := range []string{"http://" + reg.host() + "/token", "/token", "ftp://" + reg.host() + "/token"} {
+		reg.realm = realm
+
+		resolver := NewResolver().WithTransport(reg.server.Client().Transport).WithCredentials(credentials)
+
+		_, err := resolver.Pin(context.Background(), reg.host()+"/app:v1")
+		assert.ErrorContains(t, err, "is not an HTTPS URL", realm)
+	}
+
+	assert.Zero(t, reg.tokenRequests.Load(), "credentials were sent to an insecure realm")
+
+	plain := newFakeRegistry(t, false)
+	plain.manifests["app:v1"] = `{"schemaVersion": 2}`
+	plain.username, plain.password = "user", "pass"
+
+	resolver := NewResolver().WithPlainHTTP(plain.host()).WithCredentials(credentials)
+
+	_, err := resolver.Pin(context.Background(), plain.host()+"/app:v1")
+	require.NoError(t, err)
+}
+
+func TestResolverManifestSizeLimit(t *testing.T) {
+	reg := newFakeRegistry(t, true)
+	reg.manifests["app:v1"] = `{"schemaVersion": 2, "padding": "` + strings.Repeat("x", maxManifestSize) + `"}`
+
+	resolver := NewResolver().WithTransport(reg.server.Client().Transport)
+
+	ref, err := Parse(reg.host() + "/app:v1")
+	require.NoError(t, err)
+
+	_, _, _, err = resolver.FetchManifest(context.Background(), ref)
+	assert.ErrorContains(t, err, "exceeds")
+}
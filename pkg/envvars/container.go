@@ -0,0 +1,202 @@
+package envvars
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger.io/dagger"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// WithEnvVars sets the variables on the container with WithEnvVariable, in order, honoring their Expand flag.
+//
+// Parameters:
+//   - ctr: The container to set the variables on.
+//   - vars: The variables to set.
+//
+// Returns:
+//   - The container with the variables set.
+//
+// Example:
+//
+//	envVars, err := LoadDotEnv(".env")
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr = WithEnvVars(client.Container().From("alpine"), envVars)
+func WithEnvVars(ctr *dagger.Container, vars []types.DaggerEnvVars) *dagger.Container {
+	return withEnvVars(ctr, vars)
+}
+
+// envContainer is the part of *dagger.Container used to set variables.
+type envContainer[T any] interface {
+	WithEnvVariable(name, value string, opts ...dagger.ContainerWithEnvVariableOpts) T
+}
+
+// withEnvVars sets the variables on the container, in order, honoring their Expand flag.
+func withEnvVars[T envContainer[T]](ctr T, vars []types.DaggerEnvVars) T {
+	for _, v := range vars {
+		ctr = ctr.WithEnvVariable(v.Name, v.Value, dagger.ContainerWithEnvVariableOpts{Expand: v.Expand})
+	}
+
+	return ctr
+}
+
+// FromContainer reads the current environment variables of the container, in the container order.
+// Values are read as the container holds them, already expanded, so Expand is never set.
+// Secret variables (set with WithSecretVariable) are not part of the container environment variables.
+//
+// Parameters:
+//   - ctx: The context of the Dagger queries.
+//   - ctr: The container to read the variables from.
+//
+// Returns:
+//   - A slice of DaggerEnvVars, each containing the name and value of a container variable.
+//   - An error if the container cannot be evaluated.
+func FromContainer(ctx context.Context, ctr *dagger.Container) ([]types.DaggerEnvVars, error) {
+	envVariables, err := ctr.EnvVariables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the container environment variables: %w", err)
+	}
+
+	refs := make([]*dagger.EnvVariable, len(envVariables))
+	for i := range envVariables {
+		refs[i] = &envVariables[i]
+	}
+
+	return readEnvVariables(ctx, refs)
+}
+
+// envVariable is the part of *dagger.EnvVariable used to read a variable.
+type envVariable interface {
+	Name(ctx context.Context) (string, error)
+	Value(ctx context.Context) (string, error)
+}
+
+// readEnvVariables reads the name and value of the variables, in order.
+func readEnvVariables[V envVariable](ctx context.Context, envVariables []V) ([]types.DaggerEnvVars, error) {
+	vars := make([]types.DaggerEnvVars, 0, len(envVariables))
+
+	for i, envVariable := range envVariables {
+		name, err := envVariable.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the name of environment variable %d: %w", i, err)
+		}
+
+		value, err := envVariable.Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the value of environment variable %s: %w", name, err)
+		}
+
+		vars = append(vars, types.DaggerEnvVars{Name: name, Value: value})
+	}
+
+	return vars, nil
+}
+
+// EnvVarChange is a variable defined in both lists of a Diff, with a different value or Expand flag.
+type EnvVarChange struct {
+	// Before is the variable in the first list.
+	Before types.DaggerEnvVars
+	// After is the variable in the second list.
+	After types.DaggerEnvVars
+}
+
+// EnvDiff is the difference between two lists of variables. Each slice is sorted by name.
+type EnvDiff struct {
+	// Added holds the variables only defined in the second list.
+	Added []types.DaggerEnvVars
+	// Removed holds the variables only defined in the first list.
+	Removed []types.DaggerEnvVars
+	// Changed holds the variables defined in both lists with a different value or Expand flag.
+	Changed []EnvVarChange
+}
+
+// IsEmpty reports whether the lists define the same variables.
+func (d EnvDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String lists the added (+), removed (-) and changed (~) variable names, one per line,
+// without their values, which may be secrets.
+func (d EnvDiff) String() string {
+	lines := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+
+	for _, v := range d.Added {
+		lines = append(lines, "+ "+v.Name)
+	}
+
+	for _, v := range d.Removed {
+		lines = append(lines, "- "+v.Name)
+	}
+
+	for _, c := range d.Changed {
+		lines = append(lines, "~ "+c.After.Name)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares two lists of variables by name. When a list defines a variable twice, its last definition counts.
+//
+// Example:
+//
+//	before, err := FromContainer(ctx, ctr)
+//	if err != nil {
+//	    // handle error
+//	}
+//	after, err := FromContainer(ctx, WithEnvVars(ctr, envVars))
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(Diff(before, after)) // e.g., + REGION
+func Diff(before, after []types.DaggerEnvVars) EnvDiff {
+	beforeByName, afterByName := indexByName(before), indexByName(after)
+
+	var diff EnvDiff
+
+	for _, name := range sortedNames(afterByName) {
+		a := afterByName[name]
+
+		b, ok := beforeByName[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, a)
+		case b != a:
+			diff.Changed = append(diff.Changed, EnvVarChange{Before: b, After: a})
+		}
+	}
+
+	for _, name := range sortedNames(beforeByName) {
+		if _, ok := afterByName[name]; !ok {
+			diff.Removed = append(diff.Removed, beforeByName[name])
+		}
+	}
+
+	return diff
+}
+
+// indexByName indexes variables by name, the last definition of a name winning.
+func indexByName(vars []types.DaggerEnvVars) map[string]types.DaggerEnvVars {
+	byName := make(map[string]types.DaggerEnvVars, len(vars))
+	for _, v := range vars {
+		byName[v.Name] = v
+	}
+
+	return byName
+}
+
+// sortedNames returns the names of the indexed variables, sorted.
+func sortedNames(byName map[string]types.DaggerEnvVars) []string {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,143 @@
+package envvars
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestDiff(t *testing.T) {
+	before := []types.DaggerEnvVars{
+		{Name: "PATH", Value: "/usr/bin"},
+		{Name: "REMOVED", Value: "x"},
+		{Name: "SAME", Value: "1"},
+		{Name: "EXPAND", Value: "${HOME}"},
+		{Name: "DUP", Value: "old"},
+		{Name: "DUP", Value: "new"},
+	}
+	after := []types.DaggerEnvVars{
+		{Name: "SAME", Value: "1"},
+		{Name: "PATH", Value: "/usr/bin:/opt/bin"},
+		{Name: "EXPAND", Value: "${HOME}", Expand: true},
+		{Name: "ADDED_B", Value: "b"},
+		{Name: "ADDED_A", Value: "a"},
+		{Name: "DUP", Value: "new"},
+	}
+
+	diff := Diff(before, after)
+
+	assert.Equal(t, EnvDiff{
+		Added: []types.DaggerEnvVars{
+			{Name: "ADDED_A", Value: "a"},
+			{Name: "ADDED_B", Value: "b"},
+		},
+		Removed: []types.DaggerEnvVars{{Name: "REMOVED", Value: "x"}},
+		Changed: []EnvVarChange{
+			{
+				Before: types.DaggerEnvVars{Name: "EXPAND", Value: "${HOME}"},
+				After:  types.DaggerEnvVars{Name: "EXPAND", Value: "${HOME}", Expand: true},
+			},
+			{
+				Before: types.DaggerEnvVars{Name: "PATH", Value: "/usr/bin"},
+				After:  types.DaggerEnvVars{Name: "PATH", Value: "/usr/bin:/opt/bin"},
+			},
+		},
+	}, diff)
+	assert.False(t, diff.IsEmpty())
+	assert.Equal(t, "+ ADDED_A\n+ ADDED_B\n- REMOVED\n~ EXPAND\n~ PATH", diff.String())
+
+	same := Diff(before, before)
+	assert.True(t, same.IsEmpty())
+	assert.Empty(t, same.String())
+}
+
+// fakeEnvContainer is a container environment, as changed by withEnvVars. A variable set with
+// Expand has its "${NAME}" references expanded against the environment, as the engine does.
+type fakeEnvContainer struct {
+	env []fakeEnvVariable
+}
+
+func (c *fakeEnvContainer) WithEnvVariable(name, value string,
+	opts ...dagger.ContainerWithEnvVariableOpts) *fakeEnvContainer {
+	if len(opts) > 0 && opts[0].Expand {
+		value = os.Expand(value, func(ref string) string {
+			for _, v := range c.env {
+				if v.name == ref {
+					return v.value
+				}
+			}
+
+			return ""
+		})
+	}
+
+	next := &fakeEnvContainer{env: append([]fakeEnvVariable(nil), c.env...)}
+
+	for i := range next.env {
+		if next.env[i].name == name {
+			next.env[i].value = value
+			return next
+		}
+	}
+
+	next.env = append(next.env, fakeEnvVariable{name: name, value: value})
+
+	return next
+}
+
+// fakeEnvVariable is a variable read by readEnvVariables.
+type fakeEnvVariable struct {
+	name, value string
+	err         error
+}
+
+func (v fakeEnvVariable) Name(context.Context) (string, error) {
+	return v.name, nil
+}
+
+func (v fakeEnvVariable) Value(context.Context) (string, error) {
+	return v.value, v.err
+}
+
+func TestWithEnvVarsAndRead(t *testing.T) {
+	base := &fakeEnvContainer{env: []fakeEnvVariable{{name: "PATH", value: "/usr/bin"}}}
+
+	ctr := withEnvVars(base, []types.DaggerEnvVars{
+		{Name: "REGION", Value: "eu-west-1"},
+		{Name: "PATH", Value: "${PATH}:/opt/bin", Expand: true},
+		{Name: "LITERAL", Value: "${PATH}"},
+		{Name: "REGION", Value: "us-east-1"},
+	})
+
+	vars, err := readEnvVariables(context.Background(), ctr.env)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.DaggerEnvVars{
+		{Name: "PATH", Value: "/usr/bin:/opt/bin"},
+		{Name: "REGION", Value: "us-east-1"},
+		{Name: "LITERAL", Value: "${PATH}"},
+	}, vars)
+	assert.Equal(t, []fakeEnvVariable{{name: "PATH", value: "/usr/bin"}}, base.env, "the base container must not change")
+
+	before, err := readEnvVariables(context.Background(), base.env)
+	require.NoError(t, err)
+	assert.Equal(t, "+ LITERAL\n+ REGION\n~ PATH", Diff(before, vars).String())
+}
+
+func TestReadEnvVariablesError(t *testing.T) {
+	_, err := readEnvVariables(context.Background(), []fakeEnvVariable{
+		{name: "OK", value: "1"},
+		{name: "BROKEN", err: errors.New("connection lost")},
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment variable BROKEN")
+	assert.Contains(t, err.Error(), "connection lost")
+}
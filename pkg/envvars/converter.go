@@ -17,6 +17,21 @@
 //   - ToDaggerEnvVarsFromMap: Converts a map of environment variables into a slice
 //     of DaggerEnvVars.
 //
+//   - Set: Merges environment variables from defaults, dotenv files, maps, the host
+//     environment and overrides, with a defined precedence and a deterministic order.
+//
+//   - CaptureHostEnv: Snapshots the host environment filtered by allow and deny
+//     patterns, classifying secrets separately.
+//
+//   - SplitSecrets and WithEnvAndSecrets: Separate secrets (types.DaggerSecretEnvVars)
+//     from plain variables and set them on containers as Dagger secrets.
+//
+//   - WithEnvVars, FromContainer and Diff: Set variables on containers, honoring
+//     Expand, read them back and compare two lists.
+//
+//   - ParseDotEnv and LoadDotEnv: Parse dotenv content or files (quotes, escapes,
+//     multiline values, ${VAR} interpolation) into a slice of DaggerEnvVars.
+//
 // Example Usage:
 //
 // Converting a comma-separated string of environment variables:
@@ -42,11 +57,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Excoriate/daggerx/pkg/types"
+	"sort"
 	"strings"
 )
 
 // ToDaggerEnvVarsFromStr converts a comma-separated string of key=value pairs into a slice of DaggerEnvVars.
 // It ensures all entries are valid and handles empty strings gracefully.
+// Values cannot contain commas; use ParseDotEnv for values with commas, quotes or newlines.
 //
 // Parameters:
 //   - envVars: A comma-separated string of key=value pairs. For example: "key1=value1,key2=value2,key3=value3".
@@ -79,7 +96,7 @@ func ToDaggerEnvVarsFromStr(envVars string) ([]types.DaggerEnvVars, error) {
 //   - envVarsMap: A map of environment variables where each key is a variable name and each value is the corresponding value.
 //
 // Returns:
-//   - A slice of DaggerEnvVars, each containing the name and value of an environment variable.
+//   - A slice of DaggerEnvVars, each containing the name and value of an environment variable, sorted by name.
 //   - An error if the input map is empty or contains an empty key.
 //
 // Example:
@@ -95,14 +112,21 @@ func ToDaggerEnvVarsFromMap(envVarsMap map[string]string) ([]types.DaggerEnvVars
 		return nil, errors.New("input map is empty")
 	}
 
+	keys := make([]string, 0, len(envVarsMap))
+	for key := range envVarsMap {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
 	var envVars []types.DaggerEnvVars
-	for key, value := range envVarsMap {
+	for _, key := range keys {
 		if key == "" {
 			return nil, errors.New("found empty key in map")
 		}
 		envVars = append(envVars, types.DaggerEnvVars{
 			Name:  key,
-			Value: value,
+			Value: envVarsMap[key],
 		})
 	}
 	return envVars, nil
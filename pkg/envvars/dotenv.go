@@ -0,0 +1,402 @@
+package envvars
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// envNameRegex matches valid environment variable names.
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DotEnvError is a syntax error in dotenv content, with the line it occurred on.
+type DotEnvError struct {
+	// Line is the 1-based line number of the error.
+	Line int
+	// Err describes the error.
+	Err error
+}
+
+// Error returns the error prefixed with its line number.
+func (e *DotEnvError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DotEnvError) Unwrap() error {
+	return e.Err
+}
+
+// LoadDotEnv reads and parses the dotenv file at the given path. See ParseDotEnv.
+//
+// Example:
+//
+//	envVars, err := LoadDotEnv(".env")
+//	if err != nil {
+//	    // handle error
+//	}
+//	// Use envVars, e.g., fmt.Println(envVars)
+func LoadDotEnv(path string) ([]types.DaggerEnvVars, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotenv file %s: %w", path, err)
+	}
+
+	envVars, err := ParseDotEnv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dotenv file %s: %w", path, err)
+	}
+
+	return envVars, nil
+}
+
+// ParseDotEnv parses dotenv content into a slice of DaggerEnvVars, in the order the variables are defined.
+// A variable defined twice keeps its first position and takes its last value.
+//
+// The syntax follows the common dotenv conventions:
+//   - blank lines and lines starting with "#" are ignored, and so is an unquoted " #" comment after a value;
+//   - a line may start with "export ";
+//   - unquoted values are trimmed;
+//   - single-quoted values are literal;
+//   - double-quoted values support the \n, \r, \t, \", \\ and \$ escape sequences;
+//   - quoted values may span several lines;
+//   - unquoted and double-quoted values interpolate ${VAR} and $VAR.
+//
+// References to variables defined earlier in the content are replaced by their values. Other references
+// are kept as ${VAR} and the variable has Expand set, so that Dagger expands them against the container
+// environment (e.g., PATH="${PATH}:/opt/bin"). Since Dagger would expand any other "$" as well, a value
+// keeping such references cannot also hold a literal "$" (escaped, or from a substituted value).
+//
+// Parameters:
+//   - content: The dotenv content.
+//
+// Returns:
+//   - A slice of DaggerEnvVars, each containing the name, value and expand flag of a variable.
+//   - A *DotEnvError with the line number if the content is invalid.
+//
+// Example:
+//
+//	envVars, err := ParseDotEnv("export FOO=bar\nBAZ=\"${FOO}, qux\"\nPATH=$PATH:/opt/bin")
+//	if err != nil {
+//	    // handle error
+//	}
+//	fmt.Println(envVars) // Output: [{FOO bar false} {BAZ bar, qux false} {PATH ${PATH}:/opt/bin true}]
+func ParseDotEnv(content string) ([]types.DaggerEnvVars, error) {
+	p := &dotEnvParser{
+		src:     strings.ReplaceAll(content, "\r\n", "\n"),
+		line:    1,
+		indexes: map[string]int{},
+	}
+
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	return p.envVars, nil
+}
+
+// dotEnvParser parses dotenv content.
+type dotEnvParser struct {
+	src     string
+	pos     int
+	line    int
+	envVars []types.DaggerEnvVars
+	indexes map[string]int
+}
+
+// parse parses every statement of the content.
+func (p *dotEnvParser) parse() error {
+	for {
+		p.skipBlanksAndComments()
+
+		if p.pos >= len(p.src) {
+			return nil
+		}
+
+		if err := p.parseStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseStatement parses a "[export] NAME=value" statement.
+func (p *dotEnvParser) parseStatement() error {
+	line := p.line
+	rest := p.src[p.pos:]
+
+	if strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+		p.pos += len("export")
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune("= \t\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+
+	name := p.src[start:p.pos]
+	if !envNameRegex.MatchString(name) {
+		return &DotEnvError{Line: line, Err: fmt.Errorf("invalid variable name %q", name)}
+	}
+
+	p.skipSpaces()
+
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return &DotEnvError{Line: line, Err: fmt.Errorf("expected \"=\" after variable name %s", name)}
+	}
+
+	p.pos++
+	p.skipSpaces()
+
+	value, expand, err := p.parseValue()
+	if err != nil {
+		return &DotEnvError{Line: line, Err: err}
+	}
+
+	p.set(types.DaggerEnvVars{Name: name, Value: value, Expand: expand})
+
+	return nil
+}
+
+// parseValue parses a quoted or unquoted value, and the end of its line.
+func (p *dotEnvParser) parseValue() (string, bool, error) {
+	if p.pos >= len(p.src) {
+		return "", false, nil
+	}
+
+	switch quote := p.src[p.pos]; quote {
+	case '\'', '"':
+		raw, err := p.readQuoted(quote)
+		if err != nil {
+			return "", false, err
+		}
+
+		if err := p.expectLineEnd(); err != nil {
+			return "", false, err
+		}
+
+		if quote == '\'' {
+			return raw, false, nil
+		}
+
+		return p.interpolate(raw, true)
+	default:
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+
+		raw := p.src[p.pos : p.pos+end]
+		afterBlank := p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t'
+		p.pos += end
+
+		if strings.HasPrefix(raw, "#") && afterBlank {
+			return "", false, nil
+		}
+
+		if i := inlineCommentIndex(raw); i >= 0 {
+			raw = raw[:i]
+		}
+
+		return p.interpolate(strings.TrimSpace(raw), false)
+	}
+}
+
+// readQuoted reads a quoted value, possibly spanning several lines, and returns its raw content.
+// In double-quoted values, escaped quotes do not end the value; escapes are processed by interpolate.
+func (p *dotEnvParser) readQuoted(quote byte) (string, error) {
+	start := p.pos + 1
+
+	for i := start; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '\n':
+			p.line++
+		case c == '\\' && quote == '"' && i+1 < len(p.src):
+			if p.src[i+1] == '\n' {
+				p.line++
+			}
+
+			i++
+		case c == quote:
+			p.pos = i + 1
+			return p.src[start:i], nil
+		}
+	}
+
+	if quote == '"' {
+		return "", errors.New("unterminated double-quoted value")
+	}
+
+	return "", errors.New("unterminated single-quoted value")
+}
+
+// expectLineEnd checks that only blanks and a comment follow a quoted value on its line.
+func (p *dotEnvParser) expectLineEnd() error {
+	p.skipSpaces()
+
+	if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+		return nil
+	}
+
+	if p.src[p.pos] == '#' {
+		p.skipToLineEnd()
+		return nil
+	}
+
+	return fmt.Errorf("unexpected characters after quoted value: %q", p.restOfLine())
+}
+
+// interpolate processes the escape sequences (in double-quoted values) and the variable references of
+// a raw value. It reports whether references to undefined variables were kept for expansion, and returns
+// an error if the value also holds a literal "$", which the expansion would not keep literal.
+//
+//nolint:gocyclo,cyclop // A single pass over escapes and references keeps escaped "$" literal.
+func (p *dotEnvParser) interpolate(raw string, escapes bool) (string, bool, error) {
+	var (
+		value   strings.Builder
+		expand  bool
+		literal bool
+	)
+
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+
+		switch {
+		case c == '\\' && escapes && i+1 < len(raw):
+			i++
+			switch next := raw[i]; next {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\', '$':
+				value.WriteByte(next)
+				literal = literal || next == '$'
+			default:
+				value.WriteByte(c)
+				value.WriteByte(next)
+			}
+		case c == '$' && i+1 < len(raw) && raw[i+1] == '{':
+			end := strings.IndexByte(raw[i:], '}')
+			if end < 0 {
+				return "", false, errors.New("unterminated variable reference \"${\"")
+			}
+
+			name := raw[i+2 : i+end]
+			if !envNameRegex.MatchString(name) {
+				return "", false, fmt.Errorf("invalid variable reference ${%s}", name)
+			}
+
+			expand = p.writeReference(&value, name, &literal) || expand
+			i += end
+		case c == '$' && i+1 < len(raw) && isNameStart(raw[i+1]):
+			end := i + 1
+			for end < len(raw) && isNameChar(raw[end]) {
+				end++
+			}
+
+			expand = p.writeReference(&value, raw[i+1:end], &literal) || expand
+			i = end - 1
+		default:
+			value.WriteByte(c)
+			literal = literal || c == '$'
+		}
+	}
+
+	if expand && literal {
+		return "", false, errors.New("a literal \"$\" cannot be mixed with references to undefined variables, " +
+			"which are expanded in the container")
+	}
+
+	return value.String(), expand, nil
+}
+
+// writeReference writes the value of a variable defined earlier, or keeps the reference as ${NAME}
+// and returns true if the variable is not defined. It sets literal if a written value holds a "$".
+func (p *dotEnvParser) writeReference(value *strings.Builder, name string, literal *bool) bool {
+	if i, ok := p.indexes[name]; ok {
+		value.WriteString(p.envVars[i].Value)
+		*literal = *literal || strings.Contains(p.envVars[i].Value, "$")
+
+		return false
+	}
+
+	value.WriteString("${" + name + "}")
+
+	return true
+}
+
+// set adds a variable, or replaces the value of a variable already defined.
+func (p *dotEnvParser) set(envVar types.DaggerEnvVars) {
+	if i, ok := p.indexes[envVar.Name]; ok {
+		p.envVars[i] = envVar
+		return
+	}
+
+	p.indexes[envVar.Name] = len(p.envVars)
+	p.envVars = append(p.envVars, envVar)
+}
+
+// skipBlanksAndComments skips blank characters, newlines and comment lines.
+func (p *dotEnvParser) skipBlanksAndComments() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t':
+			p.pos++
+		case '\n':
+			p.line++
+			p.pos++
+		case '#':
+			p.skipToLineEnd()
+		default:
+			return
+		}
+	}
+}
+
+// skipSpaces skips spaces and tabs.
+func (p *dotEnvParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipToLineEnd moves to the end of the current line.
+func (p *dotEnvParser) skipToLineEnd() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// restOfLine returns the rest of the current line.
+func (p *dotEnvParser) restOfLine() string {
+	rest, _, _ := strings.Cut(p.src[p.pos:], "\n")
+	return rest
+}
+
+// inlineCommentIndex returns the position of a "#" comment preceded by a blank in an unquoted value, or -1.
+func inlineCommentIndex(raw string) int {
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isNameStart reports whether c can start a variable name.
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameChar reports whether c can appear in a variable name.
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
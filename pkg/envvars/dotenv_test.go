@@ -0,0 +1,143 @@
+package envvars
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []types.DaggerEnvVars
+	}{
+		{
+			name:     "Empty content",
+			content:  "\n# only a comment\n\n",
+			expected: nil,
+		},
+		{
+			name:    "Unquoted values and comments",
+			content: "# comment\nFOO=bar\nexport BAZ = qux # trailing comment\nEMPTY=\nHASH=a#b\nBLANK= # nothing\n",
+			expected: []types.DaggerEnvVars{
+				{Name: "FOO", Value: "bar"},
+				{Name: "BAZ", Value: "qux"},
+				{Name: "EMPTY", Value: ""},
+				{Name: "HASH", Value: "a#b"},
+				{Name: "BLANK", Value: ""},
+			},
+		},
+		{
+			name:    "Commas are kept",
+			content: "LIST=a,b,c\nQUOTED=\"x, y\"",
+			expected: []types.DaggerEnvVars{
+				{Name: "LIST", Value: "a,b,c"},
+				{Name: "QUOTED", Value: "x, y"},
+			},
+		},
+		{
+			name:    "Single quotes are literal",
+			content: `SINGLE='it "is" $HOME \n' # comment`,
+			expected: []types.DaggerEnvVars{
+				{Name: "SINGLE", Value: `it "is" $HOME \n`},
+			},
+		},
+		{
+			name:    "Double quote escapes",
+			content: `DOUBLE="a\nb\tc \"q\" \\ \$HOME \x"`,
+			expected: []types.DaggerEnvVars{
+				{Name: "DOUBLE", Value: "a\nb\tc \"q\" \\ $HOME \\x"},
+			},
+		},
+		{
+			name:    "Multiline values",
+			content: "CERT=\"-----BEGIN-----\nabc\n-----END-----\"\nKEY='line1\nline2'\nAFTER=1",
+			expected: []types.DaggerEnvVars{
+				{Name: "CERT", Value: "-----BEGIN-----\nabc\n-----END-----"},
+				{Name: "KEY", Value: "line1\nline2"},
+				{Name: "AFTER", Value: "1"},
+			},
+		},
+		{
+			name:    "Interpolation",
+			content: "HOST=example.com\nURL=https://${HOST}/api\nURL2=\"$HOST:8080\"\nPATH=${PATH}:/opt/bin\nLITERAL='${HOST}'",
+			expected: []types.DaggerEnvVars{
+				{Name: "HOST", Value: "example.com"},
+				{Name: "URL", Value: "https://example.com/api"},
+				{Name: "URL2", Value: "example.com:8080"},
+				{Name: "PATH", Value: "${PATH}:/opt/bin", Expand: true},
+				{Name: "LITERAL", Value: "${HOST}"},
+			},
+		},
+		{
+			name:    "Redefinition keeps the first position",
+			content: "A=1\nB=2\nA=${A}0\r\n",
+			expected: []types.DaggerEnvVars{
+				{Name: "A", Value: "10"},
+				{Name: "B", Value: "2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDotEnv(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"Missing equal sign", "FOO=bar\nINVALID", `line 2: expected "=" after variable name INVALID`},
+		{"Invalid name", "\n\n1FOO=bar", `line 3: invalid variable name "1FOO"`},
+		{"Unterminated double quote", "A=1\nB=\"abc\n\ndef", "line 2: unterminated double-quoted value"},
+		{"Unterminated single quote", "A='abc", "line 1: unterminated single-quoted value"},
+		{"Characters after quotes", "A=\"multi\nline\" extra\nB=1", `line 1: unexpected characters after quoted value: "extra"`},
+		{"Line after multiline value", "A=\"multi\nline\"\nB=${", "line 3: unterminated variable reference"},
+		{"Invalid reference", "A=${1B}", "line 1: invalid variable reference ${1B}"},
+		{"Escaped dollar with undefined reference", `A="\$HOME ${UNDEF}"`, `line 1: a literal "$" cannot be mixed`},
+		{"Literal dollar with undefined reference", "A=cost$ ${UNDEF}", `line 1: a literal "$" cannot be mixed`},
+		{"Substituted dollar with undefined reference", "A='$HOME'\nB=${A}:${UNDEF}", `line 2: a literal "$" cannot be mixed`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDotEnv(tt.content)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+
+			var dotEnvErr *DotEnvError
+			assert.True(t, errors.As(err, &dotEnvErr))
+		})
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("export FOO=bar\n"), 0o600))
+
+	result, err := LoadDotEnv(path)
+	require.NoError(t, err)
+	assert.Equal(t, []types.DaggerEnvVars{{Name: "FOO", Value: "bar"}}, result)
+
+	_, err = LoadDotEnv(filepath.Join(t.TempDir(), "missing.env"))
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("FOO"), 0o600))
+	_, err = LoadDotEnv(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1")
+}
@@ -0,0 +1,173 @@
+package envvars
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// regexPatternPrefix marks the patterns of HostEnvOptions that are regular expressions instead of globs.
+const regexPatternPrefix = "re:"
+
+// DefaultSecretPatterns are glob patterns of variable names that usually hold secrets,
+// to be used as HostEnvOptions.SecretPatterns.
+var DefaultSecretPatterns = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*_KEY",
+	"*API_KEY*",
+	"*PRIVATE_KEY*",
+	"*CREDENTIAL*",
+	"*_AUTH",
+}
+
+// HostEnvOptions selects the host environment variables captured by CaptureHostEnv.
+//
+// Patterns are globs matched against the whole variable name, where "*" matches any sequence of
+// characters and "?" any single character (e.g., "AWS_*", "TF_VAR_*"). Patterns prefixed with "re:"
+// are regular expressions, matching anywhere in the name unless anchored (e.g., "re:^GH_.*_TOKEN$").
+type HostEnvOptions struct {
+	// Allow lists the patterns of the variables to capture. Empty captures every variable.
+	Allow []string
+	// Deny lists the patterns of the variables to exclude, even if allowed (e.g., "*_SECRET").
+	Deny []string
+	// SecretPatterns lists the patterns of the captured variables classified as secrets
+	// (see DefaultSecretPatterns). Empty classifies none.
+	SecretPatterns []string
+}
+
+// HostEnv is a snapshot of host environment variables, split into plain variables and secrets.
+type HostEnv struct {
+	// Vars holds the captured variables that are not secrets, sorted by name.
+	Vars []types.DaggerEnvVars
+	// Secrets holds the captured variables matching a secret pattern, sorted by name.
+	// They are meant to be passed to containers as Dagger secrets, not as plain variables.
+	Secrets []types.DaggerEnvVars
+}
+
+// All returns the plain variables followed by the secrets.
+func (h *HostEnv) All() []types.DaggerEnvVars {
+	return append(append([]types.DaggerEnvVars(nil), h.Vars...), h.Secrets...)
+}
+
+// CaptureHostEnv snapshots the host environment (os.Environ) filtered by the allow and deny patterns,
+// and classifies the captured variables matching the secret patterns as secrets.
+//
+// Parameters:
+//   - opts: The allow, deny and secret patterns.
+//
+// Returns:
+//   - A HostEnv holding the captured plain variables and secrets, sorted by name.
+//   - An error if a pattern is invalid.
+//
+// Example:
+//
+//	hostEnv, err := CaptureHostEnv(HostEnvOptions{
+//	    Allow:          []string{"AWS_*", "TF_VAR_*"},
+//	    Deny:           []string{"*_SECRET"},
+//	    SecretPatterns: DefaultSecretPatterns,
+//	})
+//	if err != nil {
+//	    // handle error
+//	}
+//	set := NewSet().WithLayer(LayerHost, hostEnv.Vars...)
+func CaptureHostEnv(opts HostEnvOptions) (*HostEnv, error) {
+	return captureEnv(os.Environ(), opts)
+}
+
+// captureEnv captures the variables of an environment, as "NAME=value" entries. See CaptureHostEnv.
+func captureEnv(environ []string, opts HostEnvOptions) (*HostEnv, error) {
+	allow, err := compilePatterns(opts.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow pattern: %w", err)
+	}
+
+	deny, err := compilePatterns(opts.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny pattern: %w", err)
+	}
+
+	secrets, err := compilePatterns(opts.SecretPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret pattern: %w", err)
+	}
+
+	env := &HostEnv{}
+
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			continue
+		}
+
+		if (len(allow) > 0 && !matchAny(allow, name)) || matchAny(deny, name) {
+			continue
+		}
+
+		v := types.DaggerEnvVars{Name: name, Value: value}
+		if matchAny(secrets, name) {
+			env.Secrets = append(env.Secrets, v)
+		} else {
+			env.Vars = append(env.Vars, v)
+		}
+	}
+
+	sortByName(env.Vars)
+	sortByName(env.Secrets)
+
+	return env, nil
+}
+
+// compilePatterns compiles glob and "re:" patterns into regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		expr := globToRegex(pattern)
+		if regex, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+			expr = regex
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// globToRegex converts a glob, where "*" matches any sequence and "?" any character, to an anchored regular expression.
+func globToRegex(glob string) string {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+
+	return "^" + quoted + "$"
+}
+
+// matchAny reports whether the name matches one of the patterns.
+func matchAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortByName sorts variables by name.
+func sortByName(vars []types.DaggerEnvVars) {
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name < vars[j].Name
+	})
+}
@@ -0,0 +1,93 @@
+package envvars
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestCaptureEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"AWS_REGION=eu-west-1",
+		"AWS_SECRET_ACCESS_KEY=s3cr3t",
+		"AWS_SESSION_TOKEN=t0k3n",
+		"TF_VAR_name=demo=1",
+		"TF_VAR_db_SECRET=hidden",
+		"GH_TOKEN=ghp",
+		"INVALID",
+		"=empty",
+	}
+
+	tests := []struct {
+		name     string
+		opts     HostEnvOptions
+		expected *HostEnv
+	}{
+		{
+			name: "Allow and deny globs",
+			opts: HostEnvOptions{
+				Allow: []string{"AWS_*", "TF_VAR_*"},
+				Deny:  []string{"*_SECRET"},
+			},
+			expected: &HostEnv{Vars: []types.DaggerEnvVars{
+				{Name: "AWS_REGION", Value: "eu-west-1"},
+				{Name: "AWS_SECRET_ACCESS_KEY", Value: "s3cr3t"},
+				{Name: "AWS_SESSION_TOKEN", Value: "t0k3n"},
+				{Name: "TF_VAR_name", Value: "demo=1"},
+			}},
+		},
+		{
+			name: "Secrets classified",
+			opts: HostEnvOptions{
+				Allow:          []string{"AWS_*", "re:^GH_"},
+				SecretPatterns: DefaultSecretPatterns,
+			},
+			expected: &HostEnv{
+				Vars: []types.DaggerEnvVars{{Name: "AWS_REGION", Value: "eu-west-1"}},
+				Secrets: []types.DaggerEnvVars{
+					{Name: "AWS_SECRET_ACCESS_KEY", Value: "s3cr3t"},
+					{Name: "AWS_SESSION_TOKEN", Value: "t0k3n"},
+					{Name: "GH_TOKEN", Value: "ghp"},
+				},
+			},
+		},
+		{
+			name: "Empty allow captures everything not denied",
+			opts: HostEnvOptions{Deny: []string{"AWS_*", "TF_VAR_*", "GH_?OKEN"}},
+			expected: &HostEnv{Vars: []types.DaggerEnvVars{
+				{Name: "PATH", Value: "/usr/bin"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := captureEnv(environ, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCaptureHostEnv(t *testing.T) {
+	t.Setenv("DAGGERX_TEST_REGION", "eu-west-1")
+	t.Setenv("DAGGERX_TEST_TOKEN", "t0k3n")
+
+	env, err := CaptureHostEnv(HostEnvOptions{
+		Allow:          []string{"DAGGERX_TEST_*"},
+		SecretPatterns: []string{"*TOKEN"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []types.DaggerEnvVars{{Name: "DAGGERX_TEST_REGION", Value: "eu-west-1"}}, env.Vars)
+	assert.Equal(t, []types.DaggerEnvVars{{Name: "DAGGERX_TEST_TOKEN", Value: "t0k3n"}}, env.Secrets)
+	assert.Len(t, env.All(), 2)
+
+	_, err = CaptureHostEnv(HostEnvOptions{Deny: []string{"re:("}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid deny pattern")
+}
@@ -0,0 +1,166 @@
+package envvars
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"dagger.io/dagger"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// SplitSecrets splits a mixed list of variables into plain variables and secrets: the variables whose
+// name matches one of the secret patterns (see HostEnvOptions for the syntax, and DefaultSecretPatterns)
+// become secrets holding their value in memory. The order of the list is kept.
+//
+// Parameters:
+//   - vars: The variables to split.
+//   - secretPatterns: The patterns of the names of the secret variables.
+//
+// Returns:
+//   - The plain variables.
+//   - The secret variables.
+//   - An error if a pattern is invalid.
+//
+// Example:
+//
+//	plain, secrets, err := SplitSecrets(envVars, DefaultSecretPatterns)
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr, err = WithEnvAndSecrets(client, ctr, plain, secrets)
+func SplitSecrets(vars []types.DaggerEnvVars, secretPatterns []string) ([]types.DaggerEnvVars,
+	[]types.DaggerSecretEnvVars, error) {
+	patterns, err := compilePatterns(secretPatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid secret pattern: %w", err)
+	}
+
+	var (
+		plain   []types.DaggerEnvVars
+		secrets []types.DaggerSecretEnvVars
+	)
+
+	for _, v := range vars {
+		if matchAny(patterns, v.Name) {
+			secrets = append(secrets, types.DaggerSecretEnvVars{Name: v.Name, Source: types.SecretSource{Value: v.Value, HasValue: true}})
+			continue
+		}
+
+		plain = append(plain, v)
+	}
+
+	return plain, secrets, nil
+}
+
+// SecretEnvVars returns the captured secrets as secret variables read from the host environment
+// when they are applied, so their values are not copied around.
+func (h *HostEnv) SecretEnvVars() []types.DaggerSecretEnvVars {
+	secrets := make([]types.DaggerSecretEnvVars, len(h.Secrets))
+	for i, v := range h.Secrets {
+		secrets[i] = types.DaggerSecretEnvVars{Name: v.Name, Source: types.SecretSource{Env: v.Name}}
+	}
+
+	return secrets
+}
+
+// ResolveSecret reads the value of a secret variable from its source: a host environment variable,
+// a host file or the output of a host command (with trailing newlines trimmed for files and commands),
+// or the value in memory, which may be empty when HasValue is set.
+func ResolveSecret(secret types.DaggerSecretEnvVars) (string, error) {
+	source := secret.Source
+
+	hasValue := source.HasValue || source.Value != ""
+
+	set := 0
+	for _, isSet := range []bool{source.Env != "", source.File != "", len(source.Command) > 0, hasValue} {
+		if isSet {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return "", fmt.Errorf("secret %s: exactly one source (env, file, command or value) must be set, got %d",
+			secret.Name, set)
+	}
+
+	switch {
+	case source.Env != "":
+		value, ok := os.LookupEnv(source.Env)
+		if !ok {
+			return "", fmt.Errorf("secret %s: host environment variable %s is not set", secret.Name, source.Env)
+		}
+
+		return value, nil
+	case source.File != "":
+		data, err := os.ReadFile(source.File)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: failed to read file: %w", secret.Name, err)
+		}
+
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case len(source.Command) > 0:
+		return runSecretCommand(secret.Name, source.Command)
+	default:
+		return source.Value, nil
+	}
+}
+
+// WithSecrets resolves the secrets (see ResolveSecret) and sets them on the container with
+// WithSecretVariable, so their values never appear in the container definition.
+func WithSecrets(client *dagger.Client, ctr *dagger.Container,
+	secrets []types.DaggerSecretEnvVars) (*dagger.Container, error) {
+	var errs []error
+
+	for _, secret := range secrets {
+		value, err := ResolveSecret(secret)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		ctr = ctr.WithSecretVariable(secret.Name, client.SetSecret(secret.Name, value))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return ctr, nil
+}
+
+// WithEnvAndSecrets sets the plain variables on the container (see WithEnvVars),
+// and the secrets with WithSecretVariable (see WithSecrets).
+//
+// Example:
+//
+//	hostEnv, err := CaptureHostEnv(HostEnvOptions{Allow: []string{"AWS_*"}, SecretPatterns: DefaultSecretPatterns})
+//	if err != nil {
+//	    // handle error
+//	}
+//	ctr, err = WithEnvAndSecrets(client, ctr, hostEnv.Vars, hostEnv.SecretEnvVars())
+func WithEnvAndSecrets(client *dagger.Client, ctr *dagger.Container, vars []types.DaggerEnvVars,
+	secrets []types.DaggerSecretEnvVars) (*dagger.Container, error) {
+	return WithSecrets(client, WithEnvVars(ctr, vars), secrets)
+}
+
+// runSecretCommand runs a host command and returns its output, with trailing newlines trimmed.
+// The output is never included in errors.
+func runSecretCommand(name string, command types.DaggerCMD) (string, error) {
+	//nolint:gosec // The command is provided by the caller, as the secret source.
+	cmd := exec.Command(command[0], command[1:]...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("secret %s: command %s failed: %w: %s", name, command[0], err,
+			strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
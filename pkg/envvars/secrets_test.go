@@ -0,0 +1,110 @@
+package envvars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestSplitSecrets(t *testing.T) {
+	vars := []types.DaggerEnvVars{
+		{Name: "REGION", Value: "eu-west-1"},
+		{Name: "GITHUB_TOKEN", Value: "ghp"},
+		{Name: "PATH", Value: "${PATH}:/opt/bin", Expand: true},
+		{Name: "DB_PASSWORD", Value: "pw"},
+	}
+
+	plain, secrets, err := SplitSecrets(vars, DefaultSecretPatterns)
+
+	require.NoError(t, err)
+	assert.Equal(t, []types.DaggerEnvVars{
+		{Name: "REGION", Value: "eu-west-1"},
+		{Name: "PATH", Value: "${PATH}:/opt/bin", Expand: true},
+	}, plain)
+	assert.Equal(t, []types.DaggerSecretEnvVars{
+		{Name: "GITHUB_TOKEN", Source: types.SecretSource{Value: "ghp", HasValue: true}},
+		{Name: "DB_PASSWORD", Source: types.SecretSource{Value: "pw", HasValue: true}},
+	}, secrets)
+
+	_, empty, err := SplitSecrets([]types.DaggerEnvVars{{Name: "EMPTY_TOKEN"}}, DefaultSecretPatterns)
+	require.NoError(t, err)
+	require.Len(t, empty, 1)
+
+	value, err := ResolveSecret(empty[0])
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	_, _, err = SplitSecrets(vars, []string{"re:["})
+	require.Error(t, err)
+}
+
+func TestHostEnvSecretEnvVars(t *testing.T) {
+	env := &HostEnv{Secrets: []types.DaggerEnvVars{{Name: "GH_TOKEN", Value: "ghp"}}}
+
+	assert.Equal(t, []types.DaggerSecretEnvVars{
+		{Name: "GH_TOKEN", Source: types.SecretSource{Env: "GH_TOKEN"}},
+	}, env.SecretEnvVars())
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("DAGGERX_TEST_SECRET", "from-env")
+
+	file := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		source   types.SecretSource
+		expected string
+		errText  string
+	}{
+		{name: "Env", source: types.SecretSource{Env: "DAGGERX_TEST_SECRET"}, expected: "from-env"},
+		{name: "File", source: types.SecretSource{File: file}, expected: "from-file"},
+		{name: "Command", source: types.SecretSource{Command: types.DaggerCMD{"echo", "from-command"}}, expected: "from-command"},
+		{name: "Value", source: types.SecretSource{Value: "in-memory"}, expected: "in-memory"},
+		{name: "Empty value", source: types.SecretSource{HasValue: true}, expected: ""},
+		{name: "Empty value and env", source: types.SecretSource{Env: "A", HasValue: true}, errText: "got 2"},
+		{name: "No source", source: types.SecretSource{}, errText: "exactly one source"},
+		{name: "Two sources", source: types.SecretSource{Env: "A", Value: "b"}, errText: "got 2"},
+		{name: "Unset env", source: types.SecretSource{Env: "DAGGERX_TEST_UNSET"}, errText: "is not set"},
+		{name: "Missing file", source: types.SecretSource{File: file + ".missing"}, errText: "failed to read file"},
+		{
+			name:    "Failing command",
+			source:  types.SecretSource{Command: types.DaggerCMD{"sh", "-c", "echo leaked; echo oops >&2; exit 3"}},
+			errText: "oops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ResolveSecret(types.DaggerSecretEnvVars{Name: "SECRET", Source: tt.source})
+			if tt.errText != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errText)
+				assert.NotContains(t, err.Error(), "leaked")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestWithSecretsErrors(t *testing.T) {
+	// Unresolvable secrets fail before the container is used.
+	_, err := WithSecrets(nil, nil, []types.DaggerSecretEnvVars{
+		{Name: "A", Source: types.SecretSource{Env: "DAGGERX_TEST_UNSET"}},
+		{Name: "B"},
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret A")
+	assert.Contains(t, err.Error(), "secret B")
+}
@@ -0,0 +1,231 @@
+package envvars
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+// Layer is a source of environment variables in a Set. Layers with a higher value take precedence.
+type Layer int
+
+const (
+	// LayerDefaults holds the default values, with the lowest precedence.
+	LayerDefaults Layer = iota
+	// LayerFile holds the values loaded from dotenv files.
+	LayerFile
+	// LayerMap holds the values passed as maps.
+	LayerMap
+	// LayerHost holds the values read from the host environment.
+	LayerHost
+	// LayerOverrides holds the explicit overrides, with the highest precedence.
+	LayerOverrides
+)
+
+// layers lists every layer, from the lowest to the highest precedence.
+var layers = []Layer{LayerDefaults, LayerFile, LayerMap, LayerHost, LayerOverrides}
+
+// String returns the layer name.
+func (l Layer) String() string {
+	switch l {
+	case LayerDefaults:
+		return "defaults"
+	case LayerFile:
+		return "file"
+	case LayerMap:
+		return "map"
+	case LayerHost:
+		return "host"
+	case LayerOverrides:
+		return "overrides"
+	default:
+		return fmt.Sprintf("layer(%d)", int(l))
+	}
+}
+
+// Resolution is the final value of a variable in a Set, with the layer it came from.
+type Resolution struct {
+	// Var is the final variable.
+	Var types.DaggerEnvVars
+	// Layer is the layer the final value came from.
+	Layer Layer
+	// Shadowed lists the lower-precedence layers that also defined the variable, from the highest to the lowest.
+	Shadowed []Layer
+}
+
+// String describes where the value came from, without the value, which may be a secret
+// (e.g., "TF_VAR_region: host (shadows file, defaults)").
+func (r Resolution) String() string {
+	description := fmt.Sprintf("%s: %s", r.Var.Name, r.Layer)
+	if len(r.Shadowed) == 0 {
+		return description
+	}
+
+	shadowed := make([]string, len(r.Shadowed))
+	for i, l := range r.Shadowed {
+		shadowed[i] = l.String()
+	}
+
+	return fmt.Sprintf("%s (shadows %s)", description, strings.Join(shadowed, ", "))
+}
+
+// Set merges environment variables from several layers: defaults, dotenv files, maps, the host
+// environment and explicit overrides, in increasing order of precedence. Each variable is defined once,
+// with the value of the highest layer that defines it.
+//
+// Variables are returned in a deterministic order: the order in which they are first defined, walking
+// the layers from the lowest to the highest precedence, each layer in the order its variables were added
+// (map keys are sorted).
+//
+// Errors (e.g., an unreadable dotenv file) do not stop the chain; they are reported by Vars and Resolve.
+type Set struct {
+	layers map[Layer][]types.DaggerEnvVars
+	errs   []error
+}
+
+// NewSet creates an empty Set.
+//
+// Example:
+//
+//	set := NewSet().
+//	    WithDefaults(types.DaggerEnvVars{Name: "LOG_LEVEL", Value: "info"}).
+//	    WithFile(".env").
+//	    WithMap(map[string]string{"REGION": "eu-west-1"}).
+//	    WithHost("LOG_LEVEL", "CI").
+//	    WithOverrides(types.DaggerEnvVars{Name: "CI", Value: "true"})
+//	envVars, err := set.Vars()
+//	if err != nil {
+//	    // handle error
+//	}
+//	resolutions, _ := set.Resolve()
+//	for _, r := range resolutions {
+//	    fmt.Println(r) // e.g., LOG_LEVEL: host (shadows file, defaults)
+//	}
+func NewSet() *Set {
+	return &Set{layers: map[Layer][]types.DaggerEnvVars{}}
+}
+
+// WithLayer adds variables to a layer. Within a layer, a variable added again takes the new value.
+func (s *Set) WithLayer(layer Layer, vars ...types.DaggerEnvVars) *Set {
+	for _, v := range vars {
+		if v.Name == "" {
+			s.errs = append(s.errs, fmt.Errorf("%s: found empty variable name", layer))
+			continue
+		}
+
+		s.layers[layer] = setVar(s.layers[layer], v)
+	}
+
+	return s
+}
+
+// WithDefaults adds variables to the defaults layer.
+func (s *Set) WithDefaults(vars ...types.DaggerEnvVars) *Set {
+	return s.WithLayer(LayerDefaults, vars...)
+}
+
+// WithFile loads a dotenv file (see LoadDotEnv) into the file layer.
+// Files added later take precedence over files added earlier.
+func (s *Set) WithFile(path string) *Set {
+	vars, err := LoadDotEnv(path)
+	if err != nil {
+		s.errs = append(s.errs, err)
+		return s
+	}
+
+	return s.WithLayer(LayerFile, vars...)
+}
+
+// WithMap adds the variables of a map, sorted by name, to the map layer.
+func (s *Set) WithMap(vars map[string]string) *Set {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.WithLayer(LayerMap, types.DaggerEnvVars{Name: name, Value: vars[name]})
+	}
+
+	return s
+}
+
+// WithHost adds the named variables of the host environment to the host layer.
+// Variables that are not set on the host are ignored.
+func (s *Set) WithHost(names ...string) *Set {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			s.WithLayer(LayerHost, types.DaggerEnvVars{Name: name, Value: value})
+		}
+	}
+
+	return s
+}
+
+// WithOverrides adds variables to the overrides layer.
+func (s *Set) WithOverrides(vars ...types.DaggerEnvVars) *Set {
+	return s.WithLayer(LayerOverrides, vars...)
+}
+
+// Resolve merges the layers and returns, for every variable, its final value and the layer it came from.
+func (s *Set) Resolve() ([]Resolution, error) {
+	if err := errors.Join(s.errs...); err != nil {
+		return nil, fmt.Errorf("invalid environment variable set: %w", err)
+	}
+
+	var (
+		resolutions []Resolution
+		indexes     = map[string]int{}
+	)
+
+	for _, layer := range layers {
+		for _, v := range s.layers[layer] {
+			i, ok := indexes[v.Name]
+			if !ok {
+				indexes[v.Name] = len(resolutions)
+				resolutions = append(resolutions, Resolution{Var: v, Layer: layer})
+
+				continue
+			}
+
+			r := &resolutions[i]
+			r.Shadowed = append([]Layer{r.Layer}, r.Shadowed...)
+			r.Var, r.Layer = v, layer
+		}
+	}
+
+	return resolutions, nil
+}
+
+// Vars merges the layers and returns the final variables.
+func (s *Set) Vars() ([]types.DaggerEnvVars, error) {
+	resolutions, err := s.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]types.DaggerEnvVars, len(resolutions))
+	for i, r := range resolutions {
+		vars[i] = r.Var
+	}
+
+	return vars, nil
+}
+
+// setVar adds a variable to a list, or replaces the variable with the same name in place.
+func setVar(vars []types.DaggerEnvVars, v types.DaggerEnvVars) []types.DaggerEnvVars {
+	for i := range vars {
+		if vars[i].Name == v.Name {
+			vars[i] = v
+			return vars
+		}
+	}
+
+	return append(vars, v)
+}
@@ -0,0 +1,78 @@
+package envvars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Excoriate/daggerx/pkg/types"
+)
+
+func TestSetPrecedence(t *testing.T) {
+	dotEnv := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(dotEnv, []byte("LOG_LEVEL=debug\nREGION=us-east-1\nFROM_FILE=1\n"), 0o600))
+
+	t.Setenv("DAGGERX_TEST_LOG_LEVEL", "warn")
+
+	set := NewSet().
+		WithOverrides(types.DaggerEnvVars{Name: "CI", Value: "true"}).
+		WithDefaults(
+			types.DaggerEnvVars{Name: "LOG_LEVEL", Value: "info"},
+			types.DaggerEnvVars{Name: "CI", Value: "false"},
+			types.DaggerEnvVars{Name: "LOG_LEVEL", Value: "error"},
+		).
+		WithFile(dotEnv).
+		WithMap(map[string]string{"REGION": "eu-west-1", "B_MAP": "b", "A_MAP": "a"}).
+		WithHost("DAGGERX_TEST_LOG_LEVEL", "DAGGERX_TEST_UNSET").
+		WithLayer(LayerHost, types.DaggerEnvVars{Name: "LOG_LEVEL", Value: "warn"})
+
+	vars, err := set.Vars()
+	require.NoError(t, err)
+	assert.Equal(t, []types.DaggerEnvVars{
+		{Name: "LOG_LEVEL", Value: "warn"},
+		{Name: "CI", Value: "true"},
+		{Name: "REGION", Value: "eu-west-1"},
+		{Name: "FROM_FILE", Value: "1"},
+		{Name: "A_MAP", Value: "a"},
+		{Name: "B_MAP", Value: "b"},
+		{Name: "DAGGERX_TEST_LOG_LEVEL", Value: "warn"},
+	}, vars)
+
+	resolutions, err := set.Resolve()
+	require.NoError(t, err)
+
+	descriptions := make([]string, len(resolutions))
+	for i, r := range resolutions {
+		descriptions[i] = r.String()
+	}
+
+	assert.Equal(t, []string{
+		"LOG_LEVEL: host (shadows file, defaults)",
+		"CI: overrides (shadows defaults)",
+		"REGION: map (shadows file)",
+		"FROM_FILE: file",
+		"A_MAP: map",
+		"B_MAP: map",
+		"DAGGERX_TEST_LOG_LEVEL: host",
+	}, descriptions)
+	assert.Equal(t, []Layer{LayerFile, LayerDefaults}, resolutions[0].Shadowed)
+}
+
+func TestSetErrors(t *testing.T) {
+	_, err := NewSet().
+		WithFile(filepath.Join(t.TempDir(), "missing.env")).
+		WithDefaults(types.DaggerEnvVars{Value: "no name"}).
+		Vars()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing.env")
+	assert.Contains(t, err.Error(), "defaults: found empty variable name")
+}
+
+func TestLayerString(t *testing.T) {
+	assert.Equal(t, "overrides", LayerOverrides.String())
+	assert.Equal(t, "layer(9)", Layer(9).String())
+}
@@ -5,4 +5,6 @@ const (
 	// This is the latest version.
 	ImageVersion = "latest"
 	Image        = "alpine"
+	// ApkoImage is the image of the apko tool, run with ImageVersion unless a version is provided.
+	ApkoImage = "cgr.dev/chainguard/apko"
 )
@@ -9,6 +9,7 @@ type DaggerCMD []string
 // ContainerCommand is a type for a container command.
 // It contains a DaggerCMD and a boolean to enable focus.
 // If the focus is enabled, the command will add the WithFocus() method to the container's command.
+// Lists of commands are applied to a container with containerx.Plan.
 type ContainerCommand struct {
 	// CMD is the command to run in the container.
 	CMD DaggerCMD
@@ -1,6 +1,7 @@
 package types
 
 // DaggerEnvVars represents the environment variables for a Dagger task
+// Lists of variables are set on a container with envvars.WithEnvVars.
 type DaggerEnvVars struct {
 	// Name is the name of the environment variable
 	Name string
@@ -9,3 +10,27 @@ type DaggerEnvVars struct {
 	// Expand is a boolean that determines whether to expand the value of the environment variable
 	Expand bool
 }
+
+// DaggerSecretEnvVars represents a secret environment variable for a Dagger task.
+// Its value is passed to the container as a Dagger secret, so it never appears in logs or cache keys.
+type DaggerSecretEnvVars struct {
+	// Name is the name of the environment variable
+	Name string
+	// Source is where the secret value is read from
+	Source SecretSource
+}
+
+// SecretSource is where the value of a secret environment variable is read from, on the host.
+// Exactly one source must be set: Env, File, Command, or Value (a non-empty Value, or any Value with HasValue).
+type SecretSource struct {
+	// Env is the name of a host environment variable holding the secret
+	Env string
+	// File is the path of a host file holding the secret
+	File string
+	// Command is a host command whose output is the secret
+	Command DaggerCMD
+	// Value is a secret value already in memory (e.g., fetched from a vault)
+	Value string
+	// HasValue marks Value as the source even when it is empty, so an empty secret is valid
+	HasValue bool
+}